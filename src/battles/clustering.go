@@ -0,0 +1,136 @@
+// Package battles clusters kills that happen close together in time and space into battles
+// and reports the sides that fought them.
+package battles
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+const (
+	// Window is how far apart in time two kills may be and still belong to the same battle.
+	Window = 15 * time.Minute
+
+	incomingBufferSize = 1000
+	sweepBatchSize     = 1000
+)
+
+var (
+	incoming = make(chan *models.Kill, incomingBufferSize)
+
+	// assignMu serialises assignment so two kills of the same fight never create separate battles.
+	assignMu sync.Mutex
+)
+
+// Start assigns newly ingested kills to battles as they arrive, and periodically sweeps kills
+// that bypassed the listener, such as those loaded by the bulk importer.
+func Start() {
+	if !db.IsPostgres() {
+		log.Println("Battle detection needs Postgres, not starting it")
+		return
+	}
+
+	db.OnKillUpserted(func(kill *models.Kill) {
+		k := *kill
+		select {
+		case incoming <- &k:
+		default:
+			log.Printf("Battle queue full, deferring kill %d to the next sweep", kill.KillmailID)
+		}
+	})
+
+	go func() {
+		for kill := range incoming {
+			if err := Assign(kill); err != nil {
+				log.Printf("Error assigning kill %d to a battle: %v", kill.KillmailID, err)
+			}
+		}
+	}()
+
+	c := cron.New()
+	c.AddFunc("@every 10m", func() {
+		if err := AssignPending(); err != nil {
+			log.Printf("Error assigning pending kills to battles: %v", err)
+		}
+	})
+	c.Start()
+}
+
+// Assign adds the kill to the battle it belongs to. A kill joins every battle within Window of
+// its time in the same or a neighbouring system; when it bridges several they are merged.
+func Assign(kill *models.Kill) error {
+	assignMu.Lock()
+	defer assignMu.Unlock()
+
+	assigned, err := db.IsKillInBattle(kill.KillmailID)
+	if err != nil || assigned {
+		return err
+	}
+
+	nearby, err := nearbySystems(kill.SolarSystemID)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := db.GetBattlesInWindow(kill.KillTime, Window)
+	if err != nil {
+		return err
+	}
+
+	var battleIDs []uint
+	for _, battle := range candidates {
+		for _, systemID := range battle.SystemIDs {
+			if nearby[systemID] {
+				battleIDs = append(battleIDs, battle.ID)
+				break
+			}
+		}
+	}
+
+	_, err = db.AddKillToBattles(kill, battleIDs)
+	return err
+}
+
+// AssignPending assigns every stored kill not yet part of a battle, oldest first.
+func AssignPending() error {
+	total := 0
+	for {
+		kills, err := db.GetKillsWithoutBattle(sweepBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(kills) == 0 {
+			break
+		}
+
+		for i := range kills {
+			if err := Assign(&kills[i]); err != nil {
+				return err
+			}
+		}
+		total += len(kills)
+	}
+
+	if total > 0 {
+		log.Printf("Assigned %d pending kills to battles", total)
+	}
+	return nil
+}
+
+func nearbySystems(systemID int) (map[int]bool, error) {
+	adjacent, err := db.GetAdjacentSystemIDs(systemID)
+	if err != nil {
+		return nil, err
+	}
+
+	nearby := map[int]bool{systemID: true}
+	for _, id := range adjacent {
+		nearby[id] = true
+	}
+	return nearby, nil
+}
@@ -0,0 +1,361 @@
+package battles
+
+import (
+	"errors"
+	"log"
+	"sort"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/killmail"
+	"github.com/tadeasf/eve-ran/src/names"
+	"gorm.io/gorm"
+)
+
+// ShipLoss counts the ships of one type a side lost.
+type ShipLoss struct {
+	Ship  killmail.Entity `json:"ship"`
+	Count int             `json:"count"`
+	Value float64         `json:"value"`
+}
+
+// Pilot is a character who took part in the battle, with every ship they were seen in.
+type Pilot struct {
+	Character   killmail.Entity   `json:"character"`
+	Corporation *killmail.Entity  `json:"corporation,omitempty"`
+	Alliance    *killmail.Entity  `json:"alliance,omitempty"`
+	Ships       []killmail.Entity `json:"ships"`
+	Kills       int               `json:"kills"`
+	Losses      int               `json:"losses"`
+}
+
+// Side is a set of alliances, corporations and factions that fought together.
+type Side struct {
+	Alliances    []killmail.Entity `json:"alliances"`
+	Corporations []killmail.Entity `json:"corporations"`
+	Factions     []killmail.Entity `json:"factions"`
+	Pilots       []Pilot           `json:"pilots"`
+	Kills        int               `json:"kills"`
+	ShipsLost    int               `json:"ships_lost"`
+	ISKLost      float64           `json:"isk_lost"`
+	Losses       []ShipLoss        `json:"losses"`
+}
+
+// Report is a battle broken down by side.
+type Report struct {
+	Battle      models.Battle     `json:"battle"`
+	Systems     []killmail.Entity `json:"systems"`
+	Sides       []Side            `json:"sides"`
+	KillmailIDs []int64           `json:"killmail_ids"`
+}
+
+const (
+	groupAlliance    = "alliance"
+	groupCorporation = "corporation"
+	groupFaction     = "faction"
+)
+
+// group is the unit sides are built from: an alliance, or a corporation or faction outside one.
+type group struct {
+	kind string
+	id   int64
+}
+
+func groupOf(allianceID, corporationID, factionID *int) (group, bool) {
+	switch {
+	case allianceID != nil:
+		return group{groupAlliance, int64(*allianceID)}, true
+	case corporationID != nil:
+		return group{groupCorporation, int64(*corporationID)}, true
+	case factionID != nil:
+		return group{groupFaction, int64(*factionID)}, true
+	}
+	return group{}, false
+}
+
+// inferSides assigns groups to sides. Groups are placed in order of involvement, each joining
+// the side it shot alongside more than it shot at, or starting a new side otherwise.
+func inferSides(kills []models.Kill) map[group]int {
+	friendly := make(map[group]map[group]int)
+	hostile := make(map[group]map[group]int)
+	involvement := make(map[group]int)
+
+	add := func(m map[group]map[group]int, a, b group) {
+		if m[a] == nil {
+			m[a] = make(map[group]int)
+		}
+		if m[b] == nil {
+			m[b] = make(map[group]int)
+		}
+		m[a][b]++
+		m[b][a]++
+	}
+
+	for _, kill := range kills {
+		attackers := make(map[group]bool)
+		for _, attacker := range kill.Attackers {
+			if g, ok := groupOf(attacker.AllianceID, attacker.CorporationID, attacker.FactionID); ok {
+				attackers[g] = true
+			}
+		}
+
+		var attackerList []group
+		for g := range attackers {
+			involvement[g]++
+			attackerList = append(attackerList, g)
+		}
+		for i := range attackerList {
+			for j := i + 1; j < len(attackerList); j++ {
+				add(friendly, attackerList[i], attackerList[j])
+			}
+		}
+
+		victim, ok := groupOf(kill.Victim.AllianceID, kill.Victim.CorporationID, kill.Victim.FactionID)
+		if !ok {
+			continue
+		}
+		involvement[victim]++
+		for _, g := range attackerList {
+			if g != victim {
+				add(hostile, victim, g)
+			}
+		}
+	}
+
+	groups := make([]group, 0, len(involvement))
+	for g := range involvement {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if involvement[groups[i]] != involvement[groups[j]] {
+			return involvement[groups[i]] > involvement[groups[j]]
+		}
+		if groups[i].kind != groups[j].kind {
+			return groups[i].kind < groups[j].kind
+		}
+		return groups[i].id < groups[j].id
+	})
+
+	sideOf := make(map[group]int, len(groups))
+	var sides [][]group
+	for _, g := range groups {
+		best, bestScore := -1, 0
+		for i, members := range sides {
+			score := 0
+			for _, m := range members {
+				score += friendly[g][m] - hostile[g][m]
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			best = len(sides)
+			sides = append(sides, nil)
+		}
+		sides[best] = append(sides[best], g)
+		sideOf[g] = best
+	}
+
+	return sideOf
+}
+
+// BuildReport breaks the battle's kills down into sides with their pilots and losses.
+func BuildReport(battle *models.Battle) (*Report, error) {
+	kills, err := db.GetBattleKills(battle.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sideOf := inferSides(kills)
+	sideCount := 0
+	for _, side := range sideOf {
+		if side+1 > sideCount {
+			sideCount = side + 1
+		}
+	}
+
+	type pilotState struct {
+		pilot Pilot
+		side  int
+		ships map[int]bool
+	}
+	pilots := make(map[int64]*pilotState)
+	var pilotOrder []int64
+
+	trackPilot := func(characterID, corporationID, allianceID *int, shipTypeID, side int) *pilotState {
+		if characterID == nil {
+			return nil
+		}
+		id := int64(*characterID)
+		state, ok := pilots[id]
+		if !ok {
+			state = &pilotState{
+				pilot: Pilot{
+					Character:   killmail.Entity{ID: id},
+					Corporation: entityRef(corporationID),
+					Alliance:    entityRef(allianceID),
+				},
+				side:  side,
+				ships: make(map[int]bool),
+			}
+			pilots[id] = state
+			pilotOrder = append(pilotOrder, id)
+		}
+		if shipTypeID != 0 && !state.ships[shipTypeID] {
+			state.ships[shipTypeID] = true
+			state.pilot.Ships = append(state.pilot.Ships, killmail.Entity{ID: int64(shipTypeID)})
+		}
+		return state
+	}
+
+	sides := make([]Side, sideCount)
+	losses := make([]map[int]*ShipLoss, sideCount)
+	for i := range losses {
+		losses[i] = make(map[int]*ShipLoss)
+	}
+
+	report := &Report{Battle: *battle}
+	typeIDs := []int{}
+	for _, kill := range kills {
+		report.KillmailIDs = append(report.KillmailIDs, kill.KillmailID)
+
+		killers := make(map[int]bool)
+		for _, attacker := range kill.Attackers {
+			g, ok := groupOf(attacker.AllianceID, attacker.CorporationID, attacker.FactionID)
+			if !ok {
+				continue
+			}
+			side := sideOf[g]
+			killers[side] = true
+			if state := trackPilot(attacker.CharacterID, attacker.CorporationID, attacker.AllianceID, attacker.ShipTypeID, side); state != nil {
+				state.pilot.Kills++
+			}
+			typeIDs = append(typeIDs, attacker.ShipTypeID)
+		}
+		for side := range killers {
+			sides[side].Kills++
+		}
+
+		g, ok := groupOf(kill.Victim.AllianceID, kill.Victim.CorporationID, kill.Victim.FactionID)
+		if !ok {
+			continue
+		}
+		side := sideOf[g]
+		if state := trackPilot(kill.Victim.CharacterID, kill.Victim.CorporationID, kill.Victim.AllianceID, kill.Victim.ShipTypeID, side); state != nil {
+			state.pilot.Losses++
+		}
+		typeIDs = append(typeIDs, kill.Victim.ShipTypeID)
+
+		sides[side].ShipsLost++
+		sides[side].ISKLost += kill.TotalValue
+		loss, ok := losses[side][kill.Victim.ShipTypeID]
+		if !ok {
+			loss = &ShipLoss{Ship: killmail.Entity{ID: int64(kill.Victim.ShipTypeID)}}
+			losses[side][kill.Victim.ShipTypeID] = loss
+		}
+		loss.Count++
+		loss.Value += kill.TotalValue
+	}
+
+	var entityIDs []int64
+	for g := range sideOf {
+		entityIDs = append(entityIDs, g.id)
+	}
+	for _, id := range pilotOrder {
+		state := pilots[id]
+		entityIDs = append(entityIDs, id)
+		if state.pilot.Corporation != nil {
+			entityIDs = append(entityIDs, state.pilot.Corporation.ID)
+		}
+		if state.pilot.Alliance != nil {
+			entityIDs = append(entityIDs, state.pilot.Alliance.ID)
+		}
+	}
+
+	entityNames, err := names.Resolve(entityIDs)
+	if err != nil {
+		log.Printf("Error resolving names for battle %d: %v", battle.ID, err)
+	}
+	types, err := db.GetTypeInfos(typeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for g, side := range sideOf {
+		entity := killmail.Entity{ID: g.id, Name: entityNames[g.id]}
+		switch g.kind {
+		case groupAlliance:
+			sides[side].Alliances = append(sides[side].Alliances, entity)
+		case groupCorporation:
+			sides[side].Corporations = append(sides[side].Corporations, entity)
+		case groupFaction:
+			sides[side].Factions = append(sides[side].Factions, entity)
+		}
+	}
+
+	for _, id := range pilotOrder {
+		state := pilots[id]
+		pilot := state.pilot
+		pilot.Character.Name = entityNames[id]
+		if pilot.Corporation != nil {
+			pilot.Corporation.Name = entityNames[pilot.Corporation.ID]
+		}
+		if pilot.Alliance != nil {
+			pilot.Alliance.Name = entityNames[pilot.Alliance.ID]
+		}
+		for i := range pilot.Ships {
+			pilot.Ships[i].Name = types[int(pilot.Ships[i].ID)].Name
+		}
+		sides[state.side].Pilots = append(sides[state.side].Pilots, pilot)
+	}
+
+	for i := range sides {
+		for _, loss := range losses[i] {
+			loss.Ship.Name = types[int(loss.Ship.ID)].Name
+			sides[i].Losses = append(sides[i].Losses, *loss)
+		}
+		sort.Slice(sides[i].Losses, func(a, b int) bool {
+			return sides[i].Losses[a].Value > sides[i].Losses[b].Value
+		})
+		sortEntities(sides[i].Alliances)
+		sortEntities(sides[i].Corporations)
+		sortEntities(sides[i].Factions)
+	}
+	sort.SliceStable(sides, func(i, j int) bool {
+		return len(sides[i].Pilots) > len(sides[j].Pilots)
+	})
+	report.Sides = sides
+
+	report.Systems, err = systemEntities(battle.SystemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func entityRef(id *int) *killmail.Entity {
+	if id == nil {
+		return nil
+	}
+	return &killmail.Entity{ID: int64(*id)}
+}
+
+func sortEntities(entities []killmail.Entity) {
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Name < entities[j].Name })
+}
+
+func systemEntities(systemIDs []int) ([]killmail.Entity, error) {
+	systems := make([]killmail.Entity, 0, len(systemIDs))
+	for _, id := range systemIDs {
+		entity := killmail.Entity{ID: int64(id)}
+		system, err := db.GetSystemLocation(id)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		entity.Name = system.Name
+		systems = append(systems, entity)
+	}
+	return systems, nil
+}
@@ -0,0 +1,83 @@
+// Package cache stores rendered responses of expensive aggregate endpoints until new kills arrive.
+package cache
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tadeasf/eve-ran/src/db"
+)
+
+const defaultTTL = 5 * time.Minute
+
+// Entry is a rendered response.
+type Entry struct {
+	Status             int    `json:"status"`
+	ContentType        string `json:"content_type"`
+	ContentDisposition string `json:"content_disposition,omitempty"`
+	Body               []byte `json:"body"`
+}
+
+// Cache is a response store. Get returns nil on a miss; Purge drops every stored response.
+type Cache interface {
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error
+	Purge(ctx context.Context) error
+}
+
+var (
+	responses Cache
+	ttl       = defaultTTL
+	purges    = make(chan struct{}, 1)
+)
+
+// Start configures the response cache from the environment and purges it whenever kills are
+// ingested. RESPONSE_CACHE selects memory (the default), redis or none; the Redis backend
+// connects to REDIS_URL. RESPONSE_CACHE_TTL bounds how long a response is served.
+func Start() {
+	if value := os.Getenv("RESPONSE_CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Printf("Invalid RESPONSE_CACHE_TTL %q, using %s", value, defaultTTL)
+		} else {
+			ttl = parsed
+		}
+	}
+
+	switch backend := os.Getenv("RESPONSE_CACHE"); backend {
+	case "", "memory":
+		responses = NewMemory(defaultMaxEntries)
+	case "redis":
+		options, err := redis.ParseURL(os.Getenv("REDIS_URL"))
+		if err != nil {
+			log.Printf("Invalid REDIS_URL, response caching disabled: %v", err)
+			return
+		}
+		responses = NewRedis(redis.NewClient(options), "eve-ran:responses")
+	case "none":
+		return
+	default:
+		log.Printf("Unknown RESPONSE_CACHE %q, response caching disabled", backend)
+		return
+	}
+
+	// Purges are coalesced so a burst of ingested kills costs one purge at a time.
+	db.OnKillsChanged(func() {
+		select {
+		case purges <- struct{}{}:
+		default:
+		}
+	})
+
+	go func() {
+		for range purges {
+			epoch.Add(1)
+			if err := responses.Purge(context.Background()); err != nil {
+				log.Printf("Error purging response cache: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// backends returns a fresh instance of every Cache implementation. The Redis one runs against
+// an in-process miniredis, whose clock the returned function advances.
+func backends(t *testing.T) map[string]func() (Cache, func(time.Duration)) {
+	return map[string]func() (Cache, func(time.Duration)){
+		"memory": func() (Cache, func(time.Duration)) {
+			return NewMemory(defaultMaxEntries), time.Sleep
+		},
+		"redis": func() (Cache, func(time.Duration)) {
+			server := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			return NewRedis(client, "test"), server.FastForward
+		},
+	}
+}
+
+func TestCacheGetSet(t *testing.T) {
+	ctx := context.Background()
+	for name, open := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			c, _ := open()
+
+			entry, err := c.Get(ctx, "missing")
+			if err != nil || entry != nil {
+				t.Fatalf("Get on a miss = %v, %v; want nil, nil", entry, err)
+			}
+
+			stored := &Entry{Status: 200, ContentType: "application/json", ContentDisposition: "inline", Body: []byte(`{"a":1}`)}
+			if err := c.Set(ctx, "key", stored, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			entry, err = c.Get(ctx, "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry == nil || entry.Status != 200 || entry.ContentType != stored.ContentType ||
+				entry.ContentDisposition != stored.ContentDisposition || string(entry.Body) != string(stored.Body) {
+				t.Errorf("Get = %+v, want %+v", entry, stored)
+			}
+		})
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	for name, open := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			c, advance := open()
+
+			if err := c.Set(ctx, "key", &Entry{Status: 200}, 20*time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+			advance(50 * time.Millisecond)
+
+			if entry, err := c.Get(ctx, "key"); err != nil || entry != nil {
+				t.Errorf("Get after the TTL = %v, %v; want nil, nil", entry, err)
+			}
+		})
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	ctx := context.Background()
+	for name, open := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			c, _ := open()
+
+			if err := c.Set(ctx, "key", &Entry{Status: 200}, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Purge(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if entry, err := c.Get(ctx, "key"); err != nil || entry != nil {
+				t.Errorf("Get after a purge = %v, %v; want nil, nil", entry, err)
+			}
+
+			if err := c.Set(ctx, "key", &Entry{Status: 200}, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if entry, err := c.Get(ctx, "key"); err != nil || entry == nil {
+				t.Errorf("Get after storing again = %v, %v; want an entry", entry, err)
+			}
+		})
+	}
+}
+
+func TestMemoryEvictsWhenFull(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(3)
+
+	for i := 0; i < 3; i++ {
+		if err := m.Set(ctx, fmt.Sprint(i), &Entry{Status: 200}, time.Duration(i+1)*time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Set(ctx, "new", &Entry{Status: 200}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.entries) != 3 {
+		t.Errorf("memory holds %d entries, want 3", len(m.entries))
+	}
+	if entry, _ := m.Get(ctx, "0"); entry != nil {
+		t.Error("the entry closest to expiry was not evicted")
+	}
+	if entry, _ := m.Get(ctx, "new"); entry == nil {
+		t.Error("the new entry was not stored")
+	}
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultMaxEntries = 1000
+
+type memoryEntry struct {
+	entry   *Entry
+	expires time.Time
+}
+
+// Memory is an in-process Cache holding at most maxEntries responses.
+type Memory struct {
+	mu         sync.Mutex
+	entries    map[string]memoryEntry
+	maxEntries int
+}
+
+func NewMemory(maxEntries int) *Memory {
+	return &Memory{entries: make(map[string]memoryEntry), maxEntries: maxEntries}
+}
+
+func (m *Memory) Get(_ context.Context, key string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(stored.expires) {
+		delete(m.entries, key)
+		return nil, nil
+	}
+	return stored.entry, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.entries[key]; !ok && len(m.entries) >= m.maxEntries {
+		m.evict()
+	}
+	m.entries[key] = memoryEntry{entry: entry, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Purge(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = make(map[string]memoryEntry)
+	return nil
+}
+
+// evict drops expired entries, or the entry closest to expiry when none has expired.
+func (m *Memory) evict() {
+	now := time.Now()
+	oldestKey := ""
+	var oldest time.Time
+	for key, stored := range m.entries {
+		if now.After(stored.expires) {
+			delete(m.entries, key)
+			continue
+		}
+		if oldestKey == "" || stored.expires.Before(oldest) {
+			oldestKey, oldest = key, stored.expires
+		}
+	}
+	if len(m.entries) >= m.maxEntries && oldestKey != "" {
+		delete(m.entries, oldestKey)
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBodySize is the largest response kept; bigger ones, such as full exports, are passed through.
+const maxBodySize = 8 << 20
+
+// epoch counts purges, so a response computed before a purge is not stored after it.
+var epoch atomic.Int64
+
+// Responses caches successful GET responses of the wrapped handler, keyed on the path, the
+// normalized query and the headers that select the representation. Only those responses are
+// marked cacheable for clients; errors are neither stored nor marked.
+func Responses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if responses == nil || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := requestKey(c.Request)
+
+		entry, err := responses.Get(ctx, key)
+		if err != nil {
+			log.Printf("Error reading response cache: %v", err)
+		}
+		c.Header("Vary", "Accept, Accept-Language")
+		if entry != nil {
+			c.Header("Cache-Control", cacheControl())
+			c.Header("X-Cache", "HIT")
+			if entry.ContentDisposition != "" {
+				c.Header("Content-Disposition", entry.ContentDisposition)
+			}
+			c.Data(entry.Status, entry.ContentType, entry.Body)
+			c.Abort()
+			return
+		}
+
+		started := epoch.Load()
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Header("X-Cache", "MISS")
+
+		c.Next()
+
+		if recorder.Status() != http.StatusOK || recorder.overflow || c.IsAborted() || epoch.Load() != started {
+			return
+		}
+		entry = &Entry{
+			Status:             recorder.Status(),
+			ContentType:        recorder.Header().Get("Content-Type"),
+			ContentDisposition: recorder.Header().Get("Content-Disposition"),
+			Body:               recorder.body.Bytes(),
+		}
+		if err := responses.Set(context.Background(), key, entry, ttl); err != nil {
+			log.Printf("Error writing response cache: %v", err)
+		}
+	}
+}
+
+func cacheControl() string {
+	return fmt.Sprintf("public, max-age=%d", int(ttl.Seconds()))
+}
+
+// requestKey hashes the path, the query with keys and repeated values sorted, and the
+// Accept and Accept-Language headers.
+func requestKey(r *http.Request) string {
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			b.WriteString("\n" + url.QueryEscape(key) + "=" + url.QueryEscape(value))
+		}
+	}
+	b.WriteString("\nAccept: " + r.Header.Get("Accept"))
+	b.WriteString("\nAccept-Language: " + r.Header.Get("Accept-Language"))
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// bodyRecorder copies the response body as it is written, up to maxBodySize. Cache-Control is
+// added to the headers just before they are sent, once the status is known.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *bodyRecorder) writeHeader() {
+	if !r.Written() && r.Status() == http.StatusOK {
+		r.Header().Set("Cache-Control", cacheControl())
+	}
+}
+
+func (r *bodyRecorder) WriteHeaderNow() {
+	r.writeHeader()
+	r.ResponseWriter.WriteHeaderNow()
+}
+
+func (r *bodyRecorder) Flush() {
+	r.writeHeader()
+	r.ResponseWriter.Flush()
+}
+
+func (r *bodyRecorder) record(data []byte) {
+	if r.overflow {
+		return
+	}
+	if r.body.Len()+len(data) > maxBodySize {
+		r.overflow = true
+		r.body.Reset()
+		return
+	}
+	r.body.Write(data)
+}
+
+func (r *bodyRecorder) Write(data []byte) (int, error) {
+	r.writeHeader()
+	r.record(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *bodyRecorder) WriteString(s string) (int, error) {
+	r.writeHeader()
+	r.record([]byte(s))
+	return r.ResponseWriter.WriteString(s)
+}
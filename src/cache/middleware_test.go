@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter(t *testing.T) (*gin.Engine, map[string]int) {
+	gin.SetMode(gin.TestMode)
+	responses = NewMemory(defaultMaxEntries)
+	t.Cleanup(func() { responses = nil })
+
+	calls := make(map[string]int)
+	router := gin.New()
+	router.Use(Responses())
+	router.GET("/ok", func(c *gin.Context) {
+		calls["/ok"]++
+		c.JSON(http.StatusOK, gin.H{"calls": calls["/ok"]})
+	})
+	router.GET("/bad", func(c *gin.Context) {
+		calls["/bad"]++
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		calls["/fail"]++
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+	})
+	return router, calls
+}
+
+func get(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestResponsesCachesSuccess(t *testing.T) {
+	router, calls := newTestRouter(t)
+
+	first := get(router, "/ok?b=2&a=1")
+	if first.Header().Get("X-Cache") != "MISS" || first.Header().Get("Cache-Control") != cacheControl() {
+		t.Errorf("first response: X-Cache %q, Cache-Control %q", first.Header().Get("X-Cache"), first.Header().Get("Cache-Control"))
+	}
+
+	second := get(router, "/ok?a=1&b=2")
+	if second.Header().Get("X-Cache") != "HIT" || second.Header().Get("Cache-Control") != cacheControl() {
+		t.Errorf("second response: X-Cache %q, Cache-Control %q", second.Header().Get("X-Cache"), second.Header().Get("Cache-Control"))
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("cached body = %s, want %s", second.Body, first.Body)
+	}
+	if calls["/ok"] != 1 {
+		t.Errorf("handler ran %d times, want 1", calls["/ok"])
+	}
+}
+
+func TestResponsesSkipsErrors(t *testing.T) {
+	router, calls := newTestRouter(t)
+
+	for _, path := range []string{"/bad", "/fail"} {
+		for i := 0; i < 2; i++ {
+			w := get(router, path)
+			if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "" {
+				t.Errorf("%s: error response marked cacheable with %q", path, cacheControl)
+			}
+			if w.Header().Get("X-Cache") != "MISS" {
+				t.Errorf("%s: X-Cache = %q, want MISS", path, w.Header().Get("X-Cache"))
+			}
+		}
+		if calls[path] != 2 {
+			t.Errorf("%s: handler ran %d times, want 2", path, calls[path])
+		}
+	}
+}
+
+func TestResponsesDropsResponsesComputedBeforePurge(t *testing.T) {
+	router, calls := newTestRouter(t)
+	router.GET("/racing", func(c *gin.Context) {
+		calls["/racing"]++
+		epoch.Add(1)
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	get(router, "/racing")
+	get(router, "/racing")
+	if calls["/racing"] != 2 {
+		t.Errorf("handler ran %d times, want 2", calls["/racing"])
+	}
+}
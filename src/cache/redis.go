@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Cache shared between API instances. Keys are namespaced by a generation counter,
+// so a purge is a single INCR and stale responses simply expire.
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedis stores responses under prefix. Any client works, including one pointed at an
+// in-process fake.
+func NewRedis(client redis.UniversalClient, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+func (r *Redis) generationKey() string {
+	return r.prefix + ":generation"
+}
+
+func (r *Redis) entryKey(ctx context.Context, key string) (string, error) {
+	generation, err := r.client.Get(ctx, r.generationKey()).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%s", r.prefix, generation, key), nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) (*Entry, error) {
+	entryKey, err := r.entryKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.client.Get(ctx, entryKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error {
+	entryKey, err := r.entryKey(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, entryKey, data, ttl).Err()
+}
+
+func (r *Redis) Purge(ctx context.Context) error {
+	return r.client.Incr(ctx, r.generationKey()).Err()
+}
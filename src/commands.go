@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/jobs"
+)
+
+// runCommand runs a one-off maintenance command named by the first argument.
+// It returns false when no command was given and the API server should start.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	var err error
+	switch args[0] {
+	case "import":
+		err = runImport(args[1:])
+	case "rebuild-rollups":
+		err = runRebuildRollups(args[1:])
+	case "archive-kills":
+		err = runArchiveKills(args[1:])
+	case "restore-kills":
+		err = runRestoreKills(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	return true
+}
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dir := flags.String("dir", ".", "directory containing history/YYYYMMDD.json and killmails/<id>.json")
+	from := flags.String("from", "", "first date to import (YYYY-MM-DD)")
+	to := flags.String("to", "", "last date to import (YYYY-MM-DD)")
+	concurrency := flags.Int("concurrency", 10, "concurrent killmail loads")
+	fetchMissing := flags.Bool("fetch-missing", false, "fetch killmails missing from the archive from ESI")
+	flags.Parse(args)
+
+	opts := jobs.ImportOptions{Dir: *dir, Concurrency: *concurrency, FetchMissing: *fetchMissing}
+
+	var err error
+	if *from != "" {
+		if opts.From, err = time.Parse("2006-01-02", *from); err != nil {
+			return fmt.Errorf("invalid -from date: %v", err)
+		}
+	}
+	if *to != "" {
+		if opts.To, err = time.Parse("2006-01-02", *to); err != nil {
+			return fmt.Errorf("invalid -to date: %v", err)
+		}
+	}
+
+	db.InitDB()
+	return jobs.RunHistoryImport(opts)
+}
+
+func runRebuildRollups(args []string) error {
+	flags := flag.NewFlagSet("rebuild-rollups", flag.ExitOnError)
+	from := flags.String("from", "", "first day to rebuild (YYYY-MM-DD), defaults to the first stored kill")
+	to := flags.String("to", "", "last day to rebuild (YYYY-MM-DD), defaults to the last stored kill")
+	flags.Parse(args)
+
+	var fromDate, toDate time.Time
+	var err error
+	if *from != "" {
+		if fromDate, err = time.Parse("2006-01-02", *from); err != nil {
+			return fmt.Errorf("invalid -from date: %v", err)
+		}
+	}
+	if *to != "" {
+		if toDate, err = time.Parse("2006-01-02", *to); err != nil {
+			return fmt.Errorf("invalid -to date: %v", err)
+		}
+	}
+
+	db.InitDB()
+	if err := db.RebuildRollups(fromDate, toDate); err != nil {
+		return err
+	}
+	log.Println("Rebuilt daily rollups")
+	return nil
+}
+
+func runArchiveKills(args []string) error {
+	flags := flag.NewFlagSet("archive-kills", flag.ExitOnError)
+	before := flags.String("before", "", "archive the partitions of months before this one (YYYY-MM)")
+	dir := flags.String("dir", "archives", "directory the archives are written to")
+	flags.Parse(args)
+
+	cutoff, err := time.Parse("2006-01", *before)
+	if err != nil {
+		return fmt.Errorf("invalid -before month: %v", err)
+	}
+
+	db.InitDB()
+	return jobs.ArchiveKillsBefore(cutoff, *dir)
+}
+
+func runRestoreKills(args []string) error {
+	flags := flag.NewFlagSet("restore-kills", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: restore-kills <archive.ndjson.gz>...")
+	}
+
+	db.InitDB()
+	for _, path := range flags.Args() {
+		count, err := jobs.RestoreKillArchive(path)
+		if err != nil {
+			return fmt.Errorf("error restoring %s: %v", path, err)
+		}
+		log.Printf("Restored %d kills from %s", count, path)
+	}
+	return nil
+}
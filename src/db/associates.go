@@ -0,0 +1,98 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// Associate is a character, corporation or alliance seen attacking alongside a character.
+type Associate struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Kills    int       `json:"kills" gorm:"column:kills"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// associateKeys maps an entity type to its key in the attackers JSON.
+var associateKeys = map[string]string{
+	models.EntityTypeCharacter:   "character_id",
+	models.EntityTypeCorporation: "corporation_id",
+	models.EntityTypeAlliance:    "alliance_id",
+}
+
+// GetAssociates returns the entities of the given type most often found among the other attackers
+// on kills the character took part in as an attacker.
+func GetAssociates(characterID int64, entityType string, filter KillFilter, limit int) ([]Associate, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	key := associateKeys[entityType]
+	if key == "" {
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+
+	var associates []Associate
+	err := DB.Table("kills").
+		Scopes(filter.Scope()).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(kills.attackers) AS attacker").
+		Where("kills.attackers @> ?::jsonb", fmt.Sprintf(`[{"character_id": %d}]`, characterID)).
+		Where("attacker->>'character_id' IS DISTINCT FROM ?", fmt.Sprint(characterID)).
+		Where("attacker->>? IS NOT NULL", key).
+		Select("(attacker->>?)::bigint AS id, COUNT(DISTINCT kills.killmail_id) AS kills, MAX(kills.kill_time) AS last_seen", key).
+		Group("1").
+		Order("kills DESC").
+		Order("last_seen DESC").
+		Limit(limit).
+		Scan(&associates).Error
+	return associates, err
+}
+
+// CoAttackerEdge links two characters who attacked together. Source is always the lower ID.
+type CoAttackerEdge struct {
+	Source   int64     `json:"source"`
+	Target   int64     `json:"target"`
+	Kills    int       `json:"kills" gorm:"column:kills"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// GetCoAttackerEdges returns the strongest pairs of characters, at least one of them tracked, who
+// attacked together on at least minKills kills matching the filter. Only the attackers of tracked
+// characters are expanded, so the cost grows with tracked attackers rather than with the square
+// of every kill's attackers.
+func GetCoAttackerEdges(filter KillFilter, minKills, limit int) ([]CoAttackerEdge, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	var edges []CoAttackerEdge
+	err := DB.Table("kills").
+		Scopes(filter.Scope()).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(kills.attackers) AS a").
+		Joins("JOIN characters AS tracked ON tracked.id = (a->>'character_id')::bigint").
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(kills.attackers) AS b").
+		Where("b->>'character_id' IS NOT NULL AND (b->>'character_id')::bigint <> tracked.id").
+		// A pair of tracked characters is found from both sides; count it once.
+		Where("(tracked.id < (b->>'character_id')::bigint OR NOT EXISTS (SELECT 1 FROM characters WHERE characters.id = (b->>'character_id')::bigint))").
+		Select(`LEAST(tracked.id, (b->>'character_id')::bigint) AS source,
+                GREATEST(tracked.id, (b->>'character_id')::bigint) AS target,
+                COUNT(DISTINCT kills.killmail_id) AS kills,
+                MAX(kills.kill_time) AS last_seen`).
+		Group("1, 2").
+		Having("COUNT(DISTINCT kills.killmail_id) >= ?", minKills).
+		Order("kills DESC").
+		Order("last_seen DESC").
+		Limit(limit).
+		Scan(&edges).Error
+	return edges, err
+}
+
+// CharacterAssociates groups a character's most frequent co-attackers by entity type.
+type CharacterAssociates struct {
+	CharacterID  int64       `json:"character_id"`
+	Characters   []Associate `json:"characters"`
+	Corporations []Associate `json:"corporations"`
+	Alliances    []Associate `json:"alliances"`
+}
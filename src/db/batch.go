@@ -0,0 +1,152 @@
+package db
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsertChunkSize bounds the rows of one multi-row INSERT, keeping it well under the bind
+// parameter limits of Postgres and SQLite for the widest model.
+const upsertChunkSize = 500
+
+// BatchWriter accumulates rows from any number of goroutines and writes them with a batch
+// function, flushing once size rows are pending or interval has passed since the first one.
+type BatchWriter[T any] struct {
+	write    func([]T) error
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []T
+	timer   *time.Timer
+
+	// flushMu keeps batches in order when a timed flush races a full one.
+	flushMu sync.Mutex
+}
+
+func NewBatchWriter[T any](size int, interval time.Duration, write func([]T) error) *BatchWriter[T] {
+	return &BatchWriter[T]{write: write, size: size, interval: interval}
+}
+
+// Add queues a row, writing the batch on the calling goroutine when it is full.
+func (w *BatchWriter[T]) Add(row T) error {
+	w.mu.Lock()
+	w.pending = append(w.pending, row)
+	full := len(w.pending) >= w.size
+	if !full && w.timer == nil && w.interval > 0 {
+		w.timer = time.AfterFunc(w.interval, func() {
+			if err := w.Flush(); err != nil {
+				log.Printf("Error writing batch: %v", err)
+			}
+		})
+	}
+	w.mu.Unlock()
+
+	if full {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the pending rows.
+func (w *BatchWriter[T]) Flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	rows := w.pending
+	w.pending = nil
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.mu.Unlock()
+
+	if len(rows) == 0 {
+		return nil
+	}
+	return w.write(rows)
+}
+
+// Close writes the remaining rows. The writer must not be used afterwards.
+func (w *BatchWriter[T]) Close() error {
+	return w.Flush()
+}
+
+// dedupe keeps the last row for each key, as one INSERT ... ON CONFLICT cannot touch a row twice.
+func dedupe[T any, K comparable](rows []T, key func(*T) K) []T {
+	index := make(map[K]int, len(rows))
+	unique := make([]T, 0, len(rows))
+	for i := range rows {
+		k := key(&rows[i])
+		if j, ok := index[k]; ok {
+			unique[j] = rows[i]
+			continue
+		}
+		index[k] = len(unique)
+		unique = append(unique, rows[i])
+	}
+	return unique
+}
+
+// upsertRows writes rows with multi-row INSERT ... ON CONFLICT statements in one transaction.
+// Only the updates columns are overwritten on conflict.
+func upsertRows[T any](db *gorm.DB, rows []T, keys []string, updates []string) error {
+	return upsertRowsSet(db, rows, keys, clause.AssignmentColumns(updates))
+}
+
+// upsertRowsSet is upsertRows with arbitrary assignments on conflict.
+func upsertRowsSet[T any](db *gorm.DB, rows []T, keys []string, updates clause.Set) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns := make([]clause.Column, len(keys))
+	for i, key := range keys {
+		columns[i] = clause.Column{Name: key}
+	}
+	conflict := clause.OnConflict{Columns: columns, DoUpdates: updates}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(rows); start += upsertChunkSize {
+			end := min(start+upsertChunkSize, len(rows))
+			if err := tx.Clauses(conflict).Create(rows[start:end]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func UpsertRegions(regions []models.Region) error {
+	return store.UpsertRegions(regions)
+}
+
+func UpsertConstellations(constellations []models.Constellation) error {
+	return store.UpsertConstellations(constellations)
+}
+
+func UpsertSystems(systems []models.System) error {
+	return store.UpsertSystems(systems)
+}
+
+func UpsertStargates(stargates []models.Stargate) error {
+	return store.UpsertStargates(stargates)
+}
+
+func UpsertESIItems(items []models.ESIItem) error {
+	return store.UpsertESIItems(items)
+}
+
+func UpsertItemGroups(groups []models.ItemGroup) error {
+	return store.UpsertItemGroups(groups)
+}
+
+func UpsertLocalizedNames(names []models.LocalizedName) error {
+	return store.UpsertLocalizedNames(names)
+}
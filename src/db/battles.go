@@ -0,0 +1,183 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// battleColumns selects a battle with its total value summed from its kills when read, so it
+// follows the kills' values as they are refreshed.
+const battleColumns = `battles.id, battles.start_time, battles.end_time, battles.system_ids, battles.kill_count, battles.updated_at,
+    (SELECT COALESCE(SUM(kills.total_value), 0)
+     FROM battle_kills
+     JOIN kills ON kills.killmail_id = battle_kills.killmail_id
+     WHERE battle_kills.battle_id = battles.id) AS total_value`
+
+// IsKillInBattle reports whether the kill has already been assigned to a battle.
+func IsKillInBattle(killmailID int64) (bool, error) {
+	var count int64
+	err := DB.Model(&models.BattleKill{}).Where("killmail_id = ?", killmailID).Count(&count).Error
+	return count > 0, err
+}
+
+// GetBattlesInWindow returns battles whose time span, widened by margin, contains t.
+func GetBattlesInWindow(t time.Time, margin time.Duration) ([]models.Battle, error) {
+	var battles []models.Battle
+	err := DB.Where("start_time <= ? AND end_time >= ?", t.Add(margin), t.Add(-margin)).
+		Order("id").
+		Find(&battles).Error
+	return battles, err
+}
+
+// AddKillToBattles assigns the kill to a battle, merging all given battles into the oldest one.
+// A new battle is created when none are given. The battle's summary is recomputed.
+func AddKillToBattles(kill *models.Kill, battleIDs []uint) (uint, error) {
+	if err := requirePostgres(); err != nil {
+		return 0, err
+	}
+
+	var battleID uint
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if len(battleIDs) == 0 {
+			battle := models.Battle{
+				StartTime: kill.KillTime,
+				EndTime:   kill.KillTime,
+				SystemIDs: models.IntArray{kill.SolarSystemID},
+			}
+			if err := tx.Create(&battle).Error; err != nil {
+				return err
+			}
+			battleID = battle.ID
+		} else {
+			battleID = battleIDs[0]
+			for _, id := range battleIDs[1:] {
+				if id < battleID {
+					battleID = id
+				}
+			}
+
+			var merged []uint
+			for _, id := range battleIDs {
+				if id != battleID {
+					merged = append(merged, id)
+				}
+			}
+			if len(merged) > 0 {
+				err := tx.Model(&models.BattleKill{}).Where("battle_id IN ?", merged).Update("battle_id", battleID).Error
+				if err != nil {
+					return err
+				}
+				if err := tx.Delete(&models.Battle{}, merged).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.BattleKill{KillmailID: kill.KillmailID, BattleID: battleID}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+            UPDATE battles SET
+                start_time = s.start_time,
+                end_time = s.end_time,
+                kill_count = s.kill_count,
+                system_ids = s.system_ids,
+                updated_at = NOW()
+            FROM (
+                SELECT MIN(kills.kill_time) AS start_time,
+                       MAX(kills.kill_time) AS end_time,
+                       COUNT(*) AS kill_count,
+                       jsonb_agg(DISTINCT kills.solar_system_id) AS system_ids
+                FROM battle_kills
+                JOIN kills ON kills.killmail_id = battle_kills.killmail_id
+                WHERE battle_kills.battle_id = ?
+            ) s
+            WHERE battles.id = ?`, battleID, battleID).Error
+	})
+	return battleID, err
+}
+
+// GetKillsWithoutBattle returns the oldest kills not yet assigned to a battle.
+func GetKillsWithoutBattle(limit int) ([]models.Kill, error) {
+	var kills []models.Kill
+	err := DB.Model(&models.Kill{}).
+		Joins("LEFT JOIN battle_kills ON battle_kills.killmail_id = kills.killmail_id").
+		Where("battle_kills.killmail_id IS NULL").
+		Order("kills.kill_time ASC").
+		Order("kills.killmail_id ASC").
+		Limit(limit).
+		Find(&kills).Error
+	return kills, err
+}
+
+// GetBattles returns one page of battles with at least minKills kills, newest first. When the
+// filter is not empty only battles containing a matching kill are returned.
+func GetBattles(filter KillFilter, minKills, page, pageSize int) ([]models.Battle, int64, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, 0, err
+	}
+
+	var battles []models.Battle
+	var totalCount int64
+
+	matching := DB.Model(&models.Kill{}).
+		Select("battle_kills.battle_id").
+		Joins("JOIN battle_kills ON battle_kills.killmail_id = kills.killmail_id").
+		Scopes(filter.Scope())
+
+	query := DB.Model(&models.Battle{}).
+		Where("kill_count >= ?", minKills).
+		Where("id IN (?)", matching)
+
+	err := query.Count(&totalCount).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.
+		Select(battleColumns).
+		Order("start_time DESC").
+		Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&battles).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return battles, totalCount, nil
+}
+
+// GetBattle returns nil without an error when the battle does not exist.
+func GetBattle(id uint) (*models.Battle, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	var battle models.Battle
+	err := DB.Select(battleColumns).First(&battle, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &battle, nil
+}
+
+func GetBattleKills(battleID uint) ([]models.Kill, error) {
+	var kills []models.Kill
+	err := DB.Model(&models.Kill{}).
+		Joins("JOIN battle_kills ON battle_kills.killmail_id = kills.killmail_id").
+		Where("battle_kills.battle_id = ?", battleID).
+		Order("kills.kill_time ASC").
+		Find(&kills).Error
+	return kills, err
+}
@@ -0,0 +1,414 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm/clause"
+)
+
+// killColumns lists the kills columns written by bulk upserts, in COPY order.
+var killColumns = []string{
+	"killmail_id", "character_id", "corporation_id", "alliance_id", "kill_time", "solar_system_id", "location_id", "hash",
+	"fitted_value", "dropped_value", "destroyed_value", "total_value", "points", "npc", "solo", "awox",
+	"victim_alliance_id", "victim_character_id", "victim_corporation_id", "victim_faction_id",
+	"victim_damage_taken", "victim_ship_type_id", "victim_items", "victim_position", "attackers", "ingested_at",
+}
+
+func killCopyRow(kill *models.Kill) ([]interface{}, error) {
+	items, err := json.Marshal(kill.Victim.Items)
+	if err != nil {
+		return nil, err
+	}
+	position, err := json.Marshal(kill.Victim.Position)
+	if err != nil {
+		return nil, err
+	}
+	attackers, err := json.Marshal(kill.Attackers)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{
+		kill.KillmailID, kill.CharacterID, kill.CorporationID, kill.AllianceID, kill.KillTime, kill.SolarSystemID, kill.LocationID, kill.Hash,
+		kill.FittedValue, kill.DroppedValue, kill.DestroyedValue, kill.TotalValue, kill.Points, kill.NPC, kill.Solo, kill.Awox,
+		kill.Victim.AllianceID, kill.Victim.CharacterID, kill.Victim.CorporationID, kill.Victim.FactionID,
+		kill.Victim.DamageTaken, kill.Victim.ShipTypeID, items, position, attackers, kill.IngestedAt,
+	}, nil
+}
+
+// KillMerge selects the columns of a stored kill that an upsert overwrites.
+type KillMerge int
+
+const (
+	// MergeAll overwrites every column with the incoming kill, as fetched from zKillboard.
+	MergeAll KillMerge = iota
+	// MergeESI overwrites only what the ESI killmail holds. It is used for kills hydrated from
+	// ESI alone, whose zKillboard columns, such as values, points and flags, are zero.
+	MergeESI
+	// MergeZKillboard overwrites only what a zKillboard list row holds, for refreshing the values,
+	// points and flags of kills already hydrated from ESI.
+	MergeZKillboard
+)
+
+// esiKillColumns are the kills columns filled from the ESI killmail.
+var esiKillColumns = map[string]bool{
+	"solar_system_id": true, "attackers": true,
+	"victim_alliance_id": true, "victim_character_id": true, "victim_corporation_id": true, "victim_faction_id": true,
+	"victim_damage_taken": true, "victim_ship_type_id": true, "victim_items": true, "victim_position": true,
+}
+
+// zkbKillColumns are the kills columns filled from a zKillboard list row.
+var zkbKillColumns = map[string]bool{
+	"location_id": true, "hash": true, "fitted_value": true, "dropped_value": true, "destroyed_value": true,
+	"total_value": true, "points": true, "npc": true, "solo": true, "awox": true,
+}
+
+// upsertKills stamps the kills that have no ingestion time yet and writes those the retention
+// policy keeps. A stored kill keeps its first ingestion time, which orders stream replays.
+func upsertKills(kills []models.Kill, merge KillMerge) ([]int64, error) {
+	stampIngestion(kills)
+	kills = withinRetention(kills)
+	if len(kills) == 0 {
+		return nil, nil
+	}
+	return store.UpsertKills(kills, merge)
+}
+
+func stampIngestion(kills []models.Kill) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for i := range kills {
+		if kills[i].IngestedAt == nil {
+			kills[i].IngestedAt = &now
+		}
+	}
+}
+
+// UpsertKills writes a batch of kills like BulkUpsertKills and notifies the kill listeners of
+// each newly stored one, as UpsertKill does.
+func UpsertKills(kills []models.Kill) error {
+	if len(kills) == 0 {
+		return nil
+	}
+	ids, err := upsertKills(kills, MergeAll)
+	if err != nil {
+		return err
+	}
+
+	inserted := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		inserted[id] = true
+	}
+	for i := range kills {
+		if inserted[kills[i].KillmailID] {
+			delete(inserted, kills[i].KillmailID)
+			notifyKillUpserted(&kills[i])
+		}
+	}
+	notifyKillsChanged()
+	return nil
+}
+
+// BulkUpsertKills writes kills in a single transaction, by COPY into a staging table on
+// Postgres. Only change listeners are notified; this path is meant for backfills.
+func BulkUpsertKills(kills []models.Kill) error {
+	return bulkUpsertKills(kills, MergeAll)
+}
+
+// ImportKills writes kills hydrated from ESI killmails like BulkUpsertKills. Kills already
+// stored keep their zKillboard columns.
+func ImportKills(kills []models.Kill) error {
+	return bulkUpsertKills(kills, MergeESI)
+}
+
+// RefreshZKillboardKills updates the zKillboard columns of the given kills that are already
+// stored and returns the others. zKillboard list rows carry no victim or attackers, so the
+// returned kills must be hydrated from ESI before they are stored.
+func RefreshZKillboardKills(kills []models.Kill) ([]models.Kill, error) {
+	if len(kills) == 0 {
+		return nil, nil
+	}
+	ids := make([]int64, len(kills))
+	for i := range kills {
+		ids[i] = kills[i].KillmailID
+	}
+	var existing []struct {
+		KillmailID int64
+		KillTime   time.Time
+	}
+	if err := DB.Model(&models.Kill{}).Select("killmail_id, kill_time").Where("killmail_id IN ?", ids).Scan(&existing).Error; err != nil {
+		return nil, err
+	}
+	stored := make(map[int64]time.Time, len(existing))
+	for _, row := range existing {
+		stored[row.KillmailID] = row.KillTime
+	}
+
+	var refresh, pending []models.Kill
+	for _, kill := range kills {
+		if killTime, ok := stored[kill.KillmailID]; ok {
+			// List rows may lack the kill time, which places the row in its partition.
+			kill.KillTime = killTime
+			refresh = append(refresh, kill)
+		} else {
+			pending = append(pending, kill)
+		}
+	}
+	if err := bulkUpsertKills(refresh, MergeZKillboard); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// RestoreKills writes kills loaded back from an archive like BulkUpsertKills, including those
+// of months before the retention cutoff. Their rollups were kept when the partition was
+// dropped, so the caller rebuilds the rollups of the restored days afterwards.
+func RestoreKills(kills []models.Kill) error {
+	if len(kills) == 0 {
+		return nil
+	}
+	stampIngestion(kills)
+	if _, err := store.UpsertKills(kills, MergeAll); err != nil {
+		return err
+	}
+
+	notifyKillsChanged()
+	return nil
+}
+
+func bulkUpsertKills(kills []models.Kill, merge KillMerge) error {
+	if len(kills) == 0 {
+		return nil
+	}
+	if _, err := upsertKills(kills, merge); err != nil {
+		return err
+	}
+
+	notifyKillsChanged()
+	return nil
+}
+
+// copyKills COPYs kills into a temporary staging table and merges them into their partitions,
+// updating the rollups they touch in the same transaction. It returns the killmail IDs the
+// merge inserted, told apart from updated rows by xmax, which is only set on rows a conflict
+// updated.
+func copyKills(kills []models.Kill, merge KillMerge) ([]int64, error) {
+	if err := ensureKillPartitions(kills); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]interface{}, 0, len(kills))
+	for i := range kills {
+		row, err := killCopyRow(&kills[i])
+		if err != nil {
+			return nil, fmt.Errorf("error encoding kill %d: %v", kills[i].KillmailID, err)
+		}
+		rows = append(rows, row)
+	}
+
+	var links []models.KillEntity
+	if merge != MergeZKillboard {
+		tracked, err := loadTracked(DB)
+		if err != nil {
+			return nil, err
+		}
+		links = killEntities(kills, tracked)
+	}
+
+	inserted, err := mergeKillRows(rows, links, merge)
+	if isMissingPartition(err) {
+		// Another process, such as the archive command, dropped a partition this one had seen.
+		forgetKillPartitions(kills)
+		if err := ensureKillPartitions(kills); err != nil {
+			return nil, err
+		}
+		inserted, err = mergeKillRows(rows, links, merge)
+	}
+	return inserted, err
+}
+
+func mergeKillRows(rows [][]interface{}, links []models.KillEntity, merge KillMerge) ([]int64, error) {
+	var inserted []int64
+	err := withPgxConn(func(ctx context.Context, conn *pgx.Conn) error {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, `CREATE TEMP TABLE kills_staging (LIKE kills INCLUDING DEFAULTS) ON COMMIT DROP`)
+		if err != nil {
+			return fmt.Errorf("error creating staging table: %v", err)
+		}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"kills_staging"}, killColumns, pgx.CopyFromRows(rows))
+		if err != nil {
+			return fmt.Errorf("error copying kills: %v", err)
+		}
+
+		// The primary key includes kill_time, so a killmail staged with another kill time than
+		// the stored one would be stored twice. Kill times never change, so keep the stored one.
+		tag, err := tx.Exec(ctx, `
+            UPDATE kills_staging SET kill_time = kills.kill_time
+            FROM kills
+            WHERE kills.killmail_id = kills_staging.killmail_id AND kills.kill_time <> kills_staging.kill_time`)
+		if err != nil {
+			return fmt.Errorf("error matching staged kill times: %v", err)
+		}
+		if tag.RowsAffected() > 0 {
+			log.Printf("Kept the stored kill time of %d killmails staged with a different one", tag.RowsAffected())
+		}
+
+		before, after := rollupStatements("(kills.killmail_id, kills.kill_time) IN (SELECT killmail_id, kill_time FROM kills_staging)")
+		for _, statement := range before {
+			if _, err := tx.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("error updating rollups: %v", err)
+			}
+		}
+
+		upsert := upsertFromStagingSQL("kills", "kills_staging", killColumns, []string{"killmail_id", "kill_time"}, killUpdates(merge).assignments())
+		rows, err := tx.Query(ctx, upsert+` RETURNING killmail_id, xmax = 0`)
+		if err != nil {
+			return fmt.Errorf("error merging staged kills: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			var isNew bool
+			if err := rows.Scan(&id, &isNew); err != nil {
+				rows.Close()
+				return err
+			}
+			if isNew {
+				inserted = append(inserted, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error merging staged kills: %w", err)
+		}
+
+		if err := linkKillsPgx(ctx, tx, links); err != nil {
+			return err
+		}
+
+		for _, statement := range after {
+			if _, err := tx.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("error updating rollups: %v", err)
+			}
+		}
+
+		return tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// killUpdate is the value a column of a stored kill takes when the kill is upserted again.
+type killUpdate struct {
+	column string
+	value  string
+}
+
+type killUpdateList []killUpdate
+
+// keepAttribution holds when a stored kill is attributed to a tracked character. The attribution
+// columns hold a single character, corporation and alliance, and fetching a corporation or
+// alliance attributes the kill to whichever member attacker matched, so a later upsert keeps a
+// tracked character's attribution. Every entity the kill counts for is linked in kill_entities.
+const keepAttribution = `kills.character_id <> 0 AND (EXCLUDED.character_id = 0 OR EXISTS (SELECT 1 FROM characters WHERE characters.id = kills.character_id))`
+
+// killUpdates lists how the columns of a stored kill are merged with an incoming row. The
+// attribution columns move together, so a kill keeps a consistent character, corporation and
+// alliance, and the first ingestion time is kept. zKillboard list rows are not attributed, so
+// refreshing them leaves the attribution alone.
+func killUpdates(merge KillMerge) killUpdateList {
+	var updates killUpdateList
+	for _, column := range killColumns {
+		switch {
+		case column == "killmail_id", column == "kill_time", column == "ingested_at":
+		case column == "character_id", column == "corporation_id", column == "alliance_id":
+			if merge == MergeZKillboard {
+				continue
+			}
+			updates = append(updates, killUpdate{column, fmt.Sprintf("CASE WHEN %s THEN kills.%s ELSE EXCLUDED.%s END", keepAttribution, column, column)})
+		case merge == MergeAll, merge == MergeESI && esiKillColumns[column], merge == MergeZKillboard && zkbKillColumns[column]:
+			updates = append(updates, killUpdate{column, "EXCLUDED." + column})
+		}
+	}
+	return updates
+}
+
+// assignments renders the updates for a DO UPDATE SET clause.
+func (updates killUpdateList) assignments() []string {
+	assignments := make([]string, len(updates))
+	for i, update := range updates {
+		assignments[i] = update.column + " = " + update.value
+	}
+	return assignments
+}
+
+// set renders the updates for gorm's OnConflict clause.
+func (updates killUpdateList) set() clause.Set {
+	set := make(clause.Set, len(updates))
+	for i, update := range updates {
+		set[i] = clause.Assignment{Column: clause.Column{Name: update.column}, Value: clause.Expr{SQL: update.value}}
+	}
+	return set
+}
+
+// upsertFromStagingSQL builds an INSERT ... SELECT that merges a staging table into its target,
+// keeping the last staged row when the batch contains duplicates. Conflicting rows get the
+// given assignments.
+func upsertFromStagingSQL(table, staging string, columns []string, keys []string, assignments []string) string {
+	list := strings.Join(columns, ", ")
+	key := strings.Join(keys, ", ")
+
+	return fmt.Sprintf(`
+        INSERT INTO %s (%s)
+        SELECT DISTINCT ON (%s) %s FROM %s ORDER BY %s, ctid DESC
+        ON CONFLICT (%s) DO UPDATE SET %s`,
+		table, list, key, list, staging, key, key, strings.Join(assignments, ", "))
+}
+
+// withPgxConn runs fn on a dedicated pgx connection from the pool, for features such as COPY
+// that database/sql does not expose.
+func withPgxConn(fn func(ctx context.Context, conn *pgx.Conn) error) error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("bulk operations require the pgx driver")
+		}
+		return fn(ctx, stdlibConn.Conn())
+	})
+}
+
+func GetImportProgress(fileName string) (*models.ImportProgress, error) {
+	progress := models.ImportProgress{FileName: fileName}
+	err := DB.FirstOrInit(&progress, models.ImportProgress{FileName: fileName}).Error
+	return &progress, err
+}
+
+func SaveImportProgress(progress *models.ImportProgress) error {
+	return DB.Save(progress).Error
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// TestMain runs the package tests against a SQLite store in a temporary directory.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "eve-ran-db")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("SQLITE_PATH", filepath.Join(dir, "test.db"))
+	InitDB()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func storedKill(t *testing.T, killmailID int64) *models.Kill {
+	t.Helper()
+	kill, err := GetKillByKillmailID(killmailID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kill == nil {
+		t.Fatalf("kill %d not stored", killmailID)
+	}
+	return kill
+}
+
+func TestImportKillsKeepsZKillboardColumns(t *testing.T) {
+	killTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fetched := models.Kill{
+		KillmailID: 3001, CharacterID: 11, KillTime: killTime, SolarSystemID: 30000142, Hash: "abc",
+		TotalValue: 5e8, Points: 10, Solo: true,
+	}
+	if err := UpsertKills([]models.Kill{fetched}); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := models.Kill{KillmailID: 3001, KillTime: killTime, SolarSystemID: 30000144, Victim: models.Victim{ShipTypeID: 587}}
+	if err := ImportKills([]models.Kill{imported}); err != nil {
+		t.Fatal(err)
+	}
+
+	kill := storedKill(t, 3001)
+	if kill.TotalValue != 5e8 || kill.Points != 10 || !kill.Solo || kill.Hash != "abc" {
+		t.Errorf("import overwrote zKillboard columns: value %v, points %d, solo %v, hash %q", kill.TotalValue, kill.Points, kill.Solo, kill.Hash)
+	}
+	if kill.CharacterID != 11 {
+		t.Errorf("import changed the attribution to character %d", kill.CharacterID)
+	}
+	if kill.SolarSystemID != 30000144 || kill.Victim.ShipTypeID != 587 {
+		t.Errorf("import did not update ESI columns: system %d, ship %d", kill.SolarSystemID, kill.Victim.ShipTypeID)
+	}
+
+	if err := BulkUpsertKills([]models.Kill{imported}); err != nil {
+		t.Fatal(err)
+	}
+	if kill := storedKill(t, 3001); kill.TotalValue != 0 {
+		t.Errorf("full merge kept total value %v", kill.TotalValue)
+	}
+}
+
+func rollupCount(t *testing.T, table, where string, args ...interface{}) int {
+	t.Helper()
+	var count int
+	err := DB.Table(table).Select("COALESCE(SUM(kill_count), 0)").Where(where, args...).Scan(&count).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestUpsertKillsUpdatesRollupsByDelta(t *testing.T) {
+	killTime := time.Date(2024, 4, 2, 8, 0, 0, 0, time.UTC)
+	kill := models.Kill{KillmailID: 4001, CharacterID: 21, KillTime: killTime, SolarSystemID: 30000200, TotalValue: 100}
+
+	if err := UpsertKills([]models.Kill{kill}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpsertKills([]models.Kill{kill}); err != nil {
+		t.Fatal(err)
+	}
+	if n := rollupCount(t, "daily_system_stats", "solar_system_id = ?", 30000200); n != 1 {
+		t.Errorf("reingested kill counted %d times in daily_system_stats, want 1", n)
+	}
+
+	// A kill fetched for another character counts for both, and a corrected system moves it.
+	kill.CharacterID = 22
+	kill.SolarSystemID = 30000201
+	kill.TotalValue = 300
+	if err := UpsertKills([]models.Kill{kill}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, characterID := range []int64{21, 22} {
+		if n := rollupCount(t, "daily_character_stats", "character_id = ?", characterID); n != 1 {
+			t.Errorf("character %d has %d kills in daily_character_stats, want 1", characterID, n)
+		}
+	}
+	var rows int64
+	if err := DB.Table("daily_system_stats").Where("solar_system_id = ?", 30000200).Count(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rows != 0 {
+		t.Errorf("%d emptied rollup rows were kept", rows)
+	}
+
+	stats, err := GetKillTimeseries(KillFilter{SystemIDs: []int{30000201}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].KillCount != 1 || stats[0].TotalISK != 300 {
+		t.Errorf("timeseries = %+v, want one day with 1 kill worth 300", stats)
+	}
+}
+
+func TestRefreshZKillboardKillsKeepsHydratedColumns(t *testing.T) {
+	killTime := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	attackerID := 31
+	hydrated := models.Kill{
+		KillmailID: 8001, CharacterID: 31, CorporationID: 98000031, KillTime: killTime, SolarSystemID: 30000300, TotalValue: 100,
+		Victim:    models.Victim{ShipTypeID: 24690, Items: models.ItemArray{{ItemTypeID: 2048, Flag: 27}}},
+		Attackers: models.AttackersJSON{{CharacterID: &attackerID, FinalBlow: true}},
+	}
+	if err := UpsertKills([]models.Kill{hydrated}); err != nil {
+		t.Fatal(err)
+	}
+
+	// List rows carry only the zKillboard columns, and possibly no kill time.
+	rows := []models.Kill{
+		{KillmailID: 8001, CharacterID: 32, Hash: "def", TotalValue: 250, Points: 5},
+		{KillmailID: 8002, CharacterID: 32, Hash: "ghi"},
+	}
+	pending, err := RefreshZKillboardKills(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].KillmailID != 8002 {
+		t.Errorf("pending kills = %+v, want only 8002", pending)
+	}
+	if kill, _ := GetKillByKillmailID(8002); kill != nil {
+		t.Error("an unhydrated list row was stored")
+	}
+
+	kill := storedKill(t, 8001)
+	if kill.TotalValue != 250 || kill.Points != 5 || kill.Hash != "def" {
+		t.Errorf("zKillboard columns not refreshed: value %v, points %d, hash %q", kill.TotalValue, kill.Points, kill.Hash)
+	}
+	if kill.Victim.ShipTypeID != 24690 || len(kill.Victim.Items) != 1 || len(kill.Attackers) != 1 || !kill.KillTime.Equal(killTime) {
+		t.Errorf("list row overwrote the killmail: ship %d, %d items, %d attackers, time %v",
+			kill.Victim.ShipTypeID, len(kill.Victim.Items), len(kill.Attackers), kill.KillTime)
+	}
+	if kill.CharacterID != 31 || kill.CorporationID != 98000031 {
+		t.Errorf("list row changed the attribution to character %d, corporation %d", kill.CharacterID, kill.CorporationID)
+	}
+	if n := rollupCount(t, "daily_ship_type_stats", "day = ? AND ship_type_id = ?", "2024-05-10", 0); n != 0 {
+		t.Errorf("%d kills rolled up under ship type 0", n)
+	}
+	if n := rollupCount(t, "daily_ship_type_stats", "day = ? AND ship_type_id = ?", "2024-05-10", 24690); n != 1 {
+		t.Errorf("ship type rollup has %d kills, want 1", n)
+	}
+}
+
+func TestUpsertKillsSkipsMonthsBeforeRetention(t *testing.T) {
+	SetKillRetention(time.Date(2024, 7, 20, 0, 0, 0, 0, time.UTC))
+	defer SetKillRetention(time.Time{})
+
+	kills := []models.Kill{
+		{KillmailID: 8101, KillTime: time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)},
+		{KillmailID: 8102, KillTime: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if err := UpsertKills(kills); err != nil {
+		t.Fatal(err)
+	}
+	if kill, _ := GetKillByKillmailID(8101); kill != nil {
+		t.Error("a kill of an archived month was stored")
+	}
+	storedKill(t, 8102)
+
+	if err := RestoreKills(kills[:1]); err != nil {
+		t.Fatal(err)
+	}
+	storedKill(t, 8101)
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+// LocationCount is a system or region with the number of kills in it.
+type LocationCount struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Kills int    `json:"kills" gorm:"column:kills"`
+}
+
+// BiggestKill is the most valuable kill in a stats range.
+type BiggestKill struct {
+	KillmailID int64     `json:"killmail_id"`
+	KillTime   time.Time `json:"killmail_time"`
+	TotalValue float64   `json:"total_value"`
+	ShipTypeID int       `json:"ship_type_id"`
+	ShipName   string    `json:"ship_name"`
+}
+
+// SpaceCount is the share of a character's kills in one space class.
+type SpaceCount struct {
+	Space    string  `json:"space"`
+	Kills    int     `json:"kills" gorm:"column:kills"`
+	TotalISK float64 `json:"total_isk"`
+}
+
+// CharacterDetailStats summarises every kill a character took part in as an attacker.
+type CharacterDetailStats struct {
+	CharacterID   int64          `json:"character_id"`
+	KillCount     int            `json:"kill_count"`
+	TotalISK      float64        `json:"total_isk"`
+	SoloKills     int            `json:"solo_kills"`
+	FleetKills    int            `json:"fleet_kills"`
+	NPCKills      int            `json:"npc_kills"`
+	AwoxKills     int            `json:"awox_kills"`
+	FinalBlows    int            `json:"final_blows"`
+	AverageFleet  float64        `json:"average_fleet_size"`
+	AverageDamage float64        `json:"average_damage_share"`
+	TotalPoints   int            `json:"total_points"`
+	ActiveDays    int            `json:"active_days"`
+	FirstKill     *time.Time     `json:"first_kill,omitempty"`
+	LastKill      *time.Time     `json:"last_kill,omitempty"`
+	TopSystem     *LocationCount `json:"top_system,omitempty" gorm:"-"`
+	TopRegion     *LocationCount `json:"top_region,omitempty" gorm:"-"`
+	BiggestKill   *BiggestKill   `json:"biggest_kill,omitempty" gorm:"-"`
+	BySpace       []SpaceCount   `json:"by_space" gorm:"-"`
+}
+
+// characterAttackerKills selects the kills matching the filter on which the character is an
+// attacker, joined with that character's attacker entry as "attacker".
+func characterAttackerKills(characterID int64, filter KillFilter) (*gorm.DB, error) {
+	where, args, err := attackerScope(models.EntityTypeCharacter, characterID)
+	if err != nil {
+		return nil, err
+	}
+	return DB.Table("kills").
+		Scopes(filter.Scope()).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(kills.attackers) AS attacker").
+		Where(where, args...), nil
+}
+
+// GetCharacterDetailStats computes the extended stats of a character over kills matching the filter.
+func GetCharacterDetailStats(characterID int64, filter KillFilter) (*CharacterDetailStats, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	query, err := characterAttackerKills(characterID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := CharacterDetailStats{CharacterID: characterID}
+	err = query.Select(`COUNT(*) AS kill_count,
+                COALESCE(SUM(kills.total_value), 0) AS total_isk,
+                COUNT(*) FILTER (WHERE kills.solo) AS solo_kills,
+                COUNT(*) FILTER (WHERE NOT kills.solo) AS fleet_kills,
+                COUNT(*) FILTER (WHERE kills.npc) AS npc_kills,
+                COUNT(*) FILTER (WHERE kills.awox) AS awox_kills,
+                COUNT(*) FILTER (WHERE (attacker->>'final_blow')::boolean) AS final_blows,
+                COALESCE(AVG(jsonb_array_length(kills.attackers)), 0) AS average_fleet,
+                COALESCE(AVG((attacker->>'damage_done')::float8 / NULLIF(kills.victim_damage_taken, 0)), 0) AS average_damage,
+                COALESCE(SUM(kills.points), 0) AS total_points,
+                COUNT(DISTINCT DATE(kills.kill_time)) AS active_days,
+                MIN(kills.kill_time) AS first_kill,
+                MAX(kills.kill_time) AS last_kill`).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.CharacterID = characterID
+	stats.BySpace = []SpaceCount{}
+	if stats.KillCount == 0 {
+		return &stats, nil
+	}
+
+	query, err = characterAttackerKills(characterID, filter)
+	if err != nil {
+		return nil, err
+	}
+	err = query.
+		Joins("LEFT JOIN systems ON systems.system_id = kills.solar_system_id").
+		Select(spaceColumn + " AS space, COUNT(*) AS kills, COALESCE(SUM(kills.total_value), 0) AS total_isk").
+		Group(spaceColumn).
+		Order("kills DESC").
+		Scan(&stats.BySpace).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.TopSystem, err = topLocation(characterID, filter, "systems.system_id", "systems.name",
+		"JOIN systems ON systems.system_id = kills.solar_system_id"); err != nil {
+		return nil, err
+	}
+	if stats.TopRegion, err = topLocation(characterID, filter, "regions.region_id", "regions.name",
+		`JOIN systems ON systems.system_id = kills.solar_system_id
+         JOIN constellations ON constellations.constellation_id = systems.constellation_id
+         JOIN regions ON regions.region_id = constellations.region_id`); err != nil {
+		return nil, err
+	}
+
+	query, err = characterAttackerKills(characterID, filter)
+	if err != nil {
+		return nil, err
+	}
+	var biggest []BiggestKill
+	err = query.
+		Joins("LEFT JOIN esi_items ON esi_items.type_id = kills.victim_ship_type_id").
+		Select(`kills.killmail_id, kills.kill_time, kills.total_value,
+                kills.victim_ship_type_id AS ship_type_id, COALESCE(esi_items.name, '') AS ship_name`).
+		Order("kills.total_value DESC").
+		Limit(1).
+		Scan(&biggest).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(biggest) > 0 {
+		stats.BiggestKill = &biggest[0]
+	}
+
+	return &stats, nil
+}
+
+func topLocation(characterID int64, filter KillFilter, idColumn, nameColumn, joins string) (*LocationCount, error) {
+	query, err := characterAttackerKills(characterID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var top []LocationCount
+	err = query.
+		Joins(joins).
+		Select(idColumn + " AS id, " + nameColumn + " AS name, COUNT(*) AS kills").
+		Group(idColumn + ", " + nameColumn).
+		Order("kills DESC").
+		Limit(1).
+		Scan(&top).Error
+	if err != nil || len(top) == 0 {
+		return nil, err
+	}
+	return &top[0], nil
+}
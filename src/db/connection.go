@@ -6,31 +6,42 @@ import (
 	"os"
 
 	"github.com/tadeasf/eve-ran/src/db/models"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// InitDB opens the backend selected by DB_DRIVER: postgres (the default) or sqlite.
 func InitDB() {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"))
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", "postgres":
+		DB, store, err = openPostgres()
+	case "sqlite":
+		DB, store, err = openSQLite()
+	default:
+		log.Fatalf("Unknown DB_DRIVER %q", driver)
+	}
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	fmt.Println("Successfully connected to the database")
+	fmt.Printf("Successfully connected to the %s database\n", store.Name())
 
 	err = InitTables()
 	if err != nil {
 		log.Fatal("Failed to initialize tables:", err)
 	}
+
+	err = store.Migrate()
+	if err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	err = LoadUniverseCache()
+	if err != nil {
+		log.Println("Failed to load universe cache:", err)
+	}
 }
 
 func InitTables() error {
@@ -41,5 +52,22 @@ func InitTables() error {
 		&models.System{},
 		&models.Constellation{},
 		&models.ESIItem{},
+		&models.TrackedEntity{},
+		&models.KillEntity{},
+		&models.NotificationRule{},
+		&models.ImportProgress{},
+		&models.TypePrice{},
+		&models.EntityName{},
+		&models.Stargate{},
+		&models.Battle{},
+		&models.BattleKill{},
+		&models.ItemGroup{},
+		&models.SovereigntyHistory{},
+		&models.SovereigntyStructureHistory{},
+		&models.FactionWarfareHistory{},
+		&models.LocalizedName{},
+		&models.DailyCharacterStats{},
+		&models.DailySystemStats{},
+		&models.DailyShipTypeStats{},
 	)
 }
@@ -1,33 +1,24 @@
 package db
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/tadeasf/eve-ran/src/db/models"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 func InsertCharacter(character *models.Character) error {
-	return DB.Create(character).Error
+	return store.InsertCharacter(character)
 }
 
 func GetCharacterByID(id int64) (*models.Character, error) {
-	var character models.Character
-	err := DB.First(&character, id).Error
-	return &character, err
+	return store.GetCharacterByID(id)
 }
 
 func InsertKill(kill *models.Kill) error {
-	result := DB.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "killmail_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"character_id", "kill_time", "solar_system_id", "location_id", "hash", "fitted_value", "dropped_value", "destroyed_value", "total_value", "points", "npc", "solo", "awox", "victim_alliance_id", "victim_character_id", "victim_corporation_id", "victim_faction_id", "victim_damage_taken", "victim_ship_type_id", "victim_items", "victim_position", "attackers"}),
-	}).Create(kill)
-
-	if result.Error != nil {
-		return fmt.Errorf("error upserting kill: %v", result.Error)
+	if _, err := upsertKills([]models.Kill{*kill}, MergeAll); err != nil {
+		return fmt.Errorf("error upserting kill: %v", err)
 	}
 	return nil
 }
@@ -39,42 +30,11 @@ func GetKillByID(id int64) (*models.Kill, error) {
 }
 
 func GetLastKillTimeForCharacter(characterID int64) (time.Time, error) {
-	var lastKill struct {
-		KillTime time.Time
-	}
-
-	result := DB.Table("kills").
-		Where("character_id = ?", characterID).
-		Order("kill_time DESC").
-		Limit(1).
-		Select("kill_time").
-		Scan(&lastKill)
-
-	if result.Error != nil {
-		return time.Time{}, result.Error
-	}
-
-	if result.RowsAffected == 0 {
-		return time.Time{}, nil
-	}
-
-	return lastKill.KillTime, nil
+	return GetLastKillTimeForEntity(models.EntityTypeCharacter, characterID)
 }
 
 func UpsertRegion(region *models.Region) error {
-	constellationsJSON, err := json.Marshal(region.Constellations)
-	if err != nil {
-		return err
-	}
-
-	return DB.Exec(`
-        INSERT INTO regions (region_id, name, description, constellations)
-        VALUES (?, ?, ?, ?)
-        ON CONFLICT (region_id) DO UPDATE
-        SET name = EXCLUDED.name,
-            description = EXCLUDED.description,
-            constellations = EXCLUDED.constellations
-    `, region.RegionID, region.Name, region.Description, constellationsJSON).Error
+	return UpsertRegions([]models.Region{*region})
 }
 
 func GetAllRegions() ([]models.Region, error) {
@@ -87,40 +47,7 @@ func GetAllRegions() ([]models.Region, error) {
 }
 
 func UpsertSystem(system *models.System) error {
-	planetsJSON, err := json.Marshal(system.Planets)
-	if err != nil {
-		return err
-	}
-
-	stargatesJSON, err := json.Marshal(system.Stargates)
-	if err != nil {
-		return err
-	}
-
-	stationsJSON, err := json.Marshal(system.Stations)
-	if err != nil {
-		return err
-	}
-
-	positionJSON, err := json.Marshal(system.Position)
-	if err != nil {
-		return err
-	}
-
-	return DB.Exec(`
-        INSERT INTO systems (system_id, constellation_id, name, security_class, security_status, star_id, planets, stargates, stations, position)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-        ON CONFLICT (system_id) DO UPDATE
-        SET constellation_id = EXCLUDED.constellation_id,
-            name = EXCLUDED.name,
-            security_class = EXCLUDED.security_class,
-            security_status = EXCLUDED.security_status,
-            star_id = EXCLUDED.star_id,
-            planets = EXCLUDED.planets,
-            stargates = EXCLUDED.stargates,
-            stations = EXCLUDED.stations,
-            position = EXCLUDED.position
-    `, system.SystemID, system.ConstellationID, system.Name, system.SecurityClass, system.SecurityStatus, system.StarID, planetsJSON, stargatesJSON, stationsJSON, positionJSON).Error
+	return UpsertSystems([]models.System{*system})
 }
 
 func GetAllSystems() ([]models.System, error) {
@@ -129,21 +56,18 @@ func GetAllSystems() ([]models.System, error) {
 	return systems, err
 }
 
-func UpsertConstellation(constellation *models.Constellation) error {
-	systemsJSON, err := json.Marshal(constellation.Systems)
-	if err != nil {
-		return err
-	}
+func UpsertStargate(stargate *models.Stargate) error {
+	return UpsertStargates([]models.Stargate{*stargate})
+}
 
-	return DB.Exec(`
-        INSERT INTO constellations (constellation_id, name, region_id, systems, position)
-        VALUES (?, ?, ?, ?, ?)
-        ON CONFLICT (constellation_id) DO UPDATE
-        SET name = EXCLUDED.name,
-            region_id = EXCLUDED.region_id,
-            systems = EXCLUDED.systems,
-            position = EXCLUDED.position
-    `, constellation.ConstellationID, constellation.Name, constellation.RegionID, systemsJSON, constellation.Position).Error
+func GetAllStargateIDs() ([]int, error) {
+	var ids []int
+	err := DB.Model(&models.Stargate{}).Pluck("stargate_id", &ids).Error
+	return ids, err
+}
+
+func UpsertConstellation(constellation *models.Constellation) error {
+	return UpsertConstellations([]models.Constellation{*constellation})
 }
 
 func GetAllConstellations() ([]models.Constellation, error) {
@@ -153,7 +77,7 @@ func GetAllConstellations() ([]models.Constellation, error) {
 }
 
 func UpsertESIItem(item *models.ESIItem) error {
-	return DB.Save(item).Error
+	return UpsertESIItems([]models.ESIItem{*item})
 }
 
 func GetAllESIItems() ([]models.ESIItem, error) {
@@ -171,41 +95,32 @@ func GetESIItemByTypeID(typeID int) (*models.ESIItem, error) {
 	return &item, err
 }
 
-func GetKillsForCharacter(characterID int64, page, pageSize int) ([]models.Kill, error) {
-	var kills []models.Kill
-	offset := (page - 1) * pageSize
-	err := DB.Where("character_id = ?", characterID).Offset(offset).Limit(pageSize).Find(&kills).Error
-	return kills, err
+func UpsertItemGroup(group *models.ItemGroup) error {
+	return UpsertItemGroups([]models.ItemGroup{*group})
 }
 
-func GetTotalKillsForCharacter(characterID int64) (int64, error) {
-	var count int64
-	err := DB.Model(&models.Kill{}).Where("character_id = ?", characterID).Count(&count).Error
-	return count, err
+// GetMissingItemGroupIDs returns the groups referenced by stored items that have not been stored themselves.
+func GetMissingItemGroupIDs() ([]int, error) {
+	var ids []int
+	err := DB.Model(&models.ESIItem{}).
+		Where("group_id NOT IN (SELECT group_id FROM item_groups)").
+		Distinct().
+		Pluck("group_id", &ids).Error
+	return ids, err
 }
 
 func GetAllCharacters() ([]models.Character, error) {
-	var characters []models.Character
-	err := DB.Find(&characters).Error
-	return characters, err
+	return store.GetAllCharacters()
 }
 
 func GetKillByKillmailID(killmailID int64) (*models.Kill, error) {
-	var kill models.Kill
-	err := DB.First(&kill, killmailID).Error
-	return &kill, err
+	return store.GetKillByKillmailID(killmailID)
 }
 
 func UpsertKill(kill *models.Kill) error {
-	result := DB.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "killmail_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"character_id", "kill_time", "solar_system_id", "location_id", "hash", "fitted_value", "dropped_value", "destroyed_value", "total_value", "points", "npc", "solo", "awox", "victim_alliance_id", "victim_character_id", "victim_corporation_id", "victim_faction_id", "victim_damage_taken", "victim_ship_type_id", "victim_items", "victim_position", "attackers"}),
-	}).Create(kill)
-
-	if result.Error != nil {
-		return fmt.Errorf("error upserting kill: %v", result.Error)
+	if err := UpsertKills([]models.Kill{*kill}); err != nil {
+		return fmt.Errorf("error upserting kill: %v", err)
 	}
-
 	return nil
 }
 
@@ -214,3 +129,12 @@ func GetAllKills() ([]models.Kill, error) {
 	err := DB.Find(&kills).Error
 	return kills, err
 }
+
+func GetLatestKill() (*models.Kill, error) {
+	var kill models.Kill
+	err := DB.Order("kill_time DESC").First(&kill).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &kill, err
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"sync"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// KillListener is called after a kill has been stored for the first time.
+type KillListener func(kill *models.Kill)
+
+var (
+	killListenersMu sync.RWMutex
+	killListeners   []KillListener
+)
+
+// OnKillUpserted registers a listener notified after UpsertKill or UpsertKills stores a kill
+// that was not stored before. Upserting a stored kill again does not notify.
+// Listeners run synchronously on the ingesting goroutine and should hand off slow work.
+func OnKillUpserted(listener KillListener) {
+	killListenersMu.Lock()
+	defer killListenersMu.Unlock()
+	killListeners = append(killListeners, listener)
+}
+
+func notifyKillUpserted(kill *models.Kill) {
+	killListenersMu.RLock()
+	defer killListenersMu.RUnlock()
+	for _, listener := range killListeners {
+		listener(kill)
+	}
+}
+
+var (
+	changeListenersMu sync.RWMutex
+	changeListeners   []func()
+)
+
+// OnKillsChanged registers a listener notified after any ingestion path has written kills,
+// including bulk backfills that skip the per-kill listeners.
+func OnKillsChanged(listener func()) {
+	changeListenersMu.Lock()
+	defer changeListenersMu.Unlock()
+	changeListeners = append(changeListeners, listener)
+}
+
+func notifyKillsChanged() {
+	changeListenersMu.RLock()
+	defer changeListenersMu.RUnlock()
+	for _, listener := range changeListeners {
+		listener()
+	}
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A kill has a single attribution in its character_id, corporation_id and alliance_id columns,
+// which a later fetch may move. What a kill counts for is kept in kill_entities instead, which
+// filters, stats, the character rollup and the fetch watermarks read through.
+
+// linkedSQL matches kills linked to any of a list of entities of one type.
+const linkedSQL = "kills.killmail_id IN (SELECT killmail_id FROM kill_entities WHERE entity_type = ? AND entity_id IN ?)"
+
+// killEntitiesJoin joins the links of one entity type to a query over kills, as link.
+const killEntitiesJoin = "JOIN kill_entities AS link ON link.killmail_id = kills.killmail_id AND link.entity_type = ?"
+
+// trackedSet holds the IDs of the tracked characters, corporations and alliances by type.
+type trackedSet map[string]map[int64]bool
+
+func loadTracked(tx *gorm.DB) (trackedSet, error) {
+	tracked := trackedSet{
+		models.EntityTypeCharacter:   {},
+		models.EntityTypeCorporation: {},
+		models.EntityTypeAlliance:    {},
+	}
+
+	var characterIDs []int64
+	if err := tx.Model(&models.Character{}).Pluck("id", &characterIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range characterIDs {
+		tracked[models.EntityTypeCharacter][id] = true
+	}
+
+	var entities []models.TrackedEntity
+	if err := tx.Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	for _, entity := range entities {
+		if ids, ok := tracked[entity.EntityType]; ok {
+			ids[entity.EntityID] = true
+		}
+	}
+	return tracked, nil
+}
+
+// killEntities returns the links of a batch of kills: the entities each kill is attributed to
+// and the tracked ones among its attackers.
+func killEntities(kills []models.Kill, tracked trackedSet) []models.KillEntity {
+	type key struct {
+		entityType string
+		entityID   int64
+		killmailID int64
+	}
+	index := make(map[key]int)
+	var links []models.KillEntity
+
+	add := func(kill *models.Kill, entityType string, entityID, characterID int64, finalBlow bool) {
+		if entityID == 0 {
+			return
+		}
+		k := key{entityType, entityID, kill.KillmailID}
+		if i, ok := index[k]; ok {
+			if finalBlow && characterID != 0 {
+				links[i].CharacterID = characterID
+			}
+			return
+		}
+		index[k] = len(links)
+		links = append(links, models.KillEntity{
+			EntityType: entityType, EntityID: entityID, KillmailID: kill.KillmailID, KillTime: kill.KillTime, CharacterID: characterID,
+		})
+	}
+
+	for i := range kills {
+		kill := &kills[i]
+		for _, attacker := range kill.Attackers {
+			var characterID int64
+			if attacker.CharacterID != nil {
+				characterID = int64(*attacker.CharacterID)
+			}
+			for _, entity := range []struct {
+				entityType string
+				id         *int
+			}{
+				{models.EntityTypeCharacter, attacker.CharacterID},
+				{models.EntityTypeCorporation, attacker.CorporationID},
+				{models.EntityTypeAlliance, attacker.AllianceID},
+			} {
+				if entity.id != nil && tracked[entity.entityType][int64(*entity.id)] {
+					add(kill, entity.entityType, int64(*entity.id), characterID, attacker.FinalBlow)
+				}
+			}
+		}
+		add(kill, models.EntityTypeCharacter, kill.CharacterID, kill.CharacterID, false)
+		add(kill, models.EntityTypeCorporation, kill.CorporationID, kill.CharacterID, false)
+		add(kill, models.EntityTypeAlliance, kill.AllianceID, kill.CharacterID, false)
+	}
+	return links
+}
+
+// linkKills writes the links of kills merged on SQLite, within the merging transaction.
+func linkKills(tx *gorm.DB, kills []models.Kill) error {
+	tracked, err := loadTracked(tx)
+	if err != nil {
+		return err
+	}
+	links := killEntities(kills, tracked)
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(links, upsertChunkSize).Error
+}
+
+// linkKillsPgx writes the links of kills merged on Postgres, within the merging transaction.
+// The kill time is read back from kills, as the merge keeps the stored one.
+func linkKillsPgx(ctx context.Context, tx pgx.Tx, links []models.KillEntity) error {
+	if len(links) == 0 {
+		return nil
+	}
+	types := make([]string, len(links))
+	entityIDs := make([]int64, len(links))
+	killmailIDs := make([]int64, len(links))
+	characterIDs := make([]int64, len(links))
+	for i, link := range links {
+		types[i], entityIDs[i], killmailIDs[i], characterIDs[i] = link.EntityType, link.EntityID, link.KillmailID, link.CharacterID
+	}
+
+	_, err := tx.Exec(ctx, `
+        INSERT INTO kill_entities (entity_type, entity_id, killmail_id, kill_time, character_id)
+        SELECT link.entity_type, link.entity_id, link.killmail_id, kills.kill_time, link.character_id
+        FROM unnest($1::text[], $2::bigint[], $3::bigint[], $4::bigint[]) AS link(entity_type, entity_id, killmail_id, character_id)
+        JOIN kills ON kills.killmail_id = link.killmail_id
+        ON CONFLICT (entity_type, entity_id, killmail_id) DO NOTHING`,
+		types, entityIDs, killmailIDs, characterIDs)
+	if err != nil {
+		return fmt.Errorf("error linking kills: %v", err)
+	}
+	return nil
+}
+
+// BackfillKillEntities links the kills stored before kill_entities existed to the entities they
+// are attributed to. Kills involving other tracked entities are linked to them when fetched
+// again.
+func BackfillKillEntities() error {
+	var count int64
+	if err := DB.Model(&models.KillEntity{}).Limit(1).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	for _, entityType := range []string{models.EntityTypeCharacter, models.EntityTypeCorporation, models.EntityTypeAlliance} {
+		column := "kills." + entityColumn(entityType)
+		err := DB.Exec(fmt.Sprintf(`
+            INSERT INTO kill_entities (entity_type, entity_id, killmail_id, kill_time, character_id)
+            SELECT ?, %s, kills.killmail_id, kills.kill_time, kills.character_id
+            FROM kills
+            WHERE %s <> 0
+            ON CONFLICT (entity_type, entity_id, killmail_id) DO NOTHING`, column, column), entityType).Error
+		if err != nil {
+			return fmt.Errorf("error linking kills to %ss: %v", entityType, err)
+		}
+	}
+	log.Println("Linked stored kills to the entities they are attributed to")
+	return nil
+}
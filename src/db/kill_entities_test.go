@@ -0,0 +1,106 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+func TestKillsCountForEveryTrackedEntity(t *testing.T) {
+	const corpA, corpB = 98100001, 98100002
+	for _, id := range []int64{corpA, corpB} {
+		if err := InsertTrackedEntity(&models.TrackedEntity{EntityType: models.EntityTypeCorporation, EntityID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pilotA, pilotB, pilotC := 51, 52, 53
+	corpAID, corpBID := corpA, corpB
+	killTime := time.Date(2024, 8, 3, 20, 0, 0, 0, time.UTC)
+	kill := models.Kill{
+		KillmailID: 8201, KillTime: killTime, SolarSystemID: 30000500, TotalValue: 1000,
+		Attackers: models.AttackersJSON{
+			{CharacterID: &pilotA, CorporationID: &corpAID},
+			{CharacterID: &pilotB, CorporationID: &corpBID, FinalBlow: true},
+			{CharacterID: &pilotC, CorporationID: &corpBID},
+		},
+	}
+
+	// Each corporation's fetch attributes the kill to its own member; the last one wins the columns.
+	kill.ResolveAffiliation(models.EntityTypeCorporation, corpA)
+	if err := UpsertKills([]models.Kill{kill}); err != nil {
+		t.Fatal(err)
+	}
+	kill.ResolveAffiliation(models.EntityTypeCorporation, corpB)
+	if err := UpsertKills([]models.Kill{kill}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int64{corpA, corpB} {
+		kills, total, err := GetKills(KillFilter{CorporationIDs: []int64{id}}, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 1 || len(kills) != 1 || kills[0].KillmailID != 8201 {
+			t.Errorf("corporation %d kills = %d of %d, want killmail 8201", id, len(kills), total)
+		}
+
+		last, err := GetLastKillTimeForEntity(models.EntityTypeCorporation, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !last.Equal(killTime) {
+			t.Errorf("corporation %d last kill time = %v, want %v", id, last, killTime)
+		}
+	}
+
+	stats, err := GetEntityStats(models.EntityTypeCorporation, KillFilter{CorporationIDs: []int64{corpA, corpB}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[int64]EntityStats)
+	for _, s := range stats {
+		byID[s.EntityID] = s
+	}
+	for _, id := range []int64{corpA, corpB} {
+		if byID[id].KillCount != 1 || byID[id].TotalISK != 1000 || byID[id].MemberCount != 1 {
+			t.Errorf("corporation %d stats = %+v, want 1 kill worth 1000 by 1 member", id, byID[id])
+		}
+	}
+}
+
+func TestKillEntitiesPreferFinalBlow(t *testing.T) {
+	pilotA, pilotB, corp := 61, 62, 98200001
+	kill := models.Kill{
+		KillmailID: 1,
+		Attackers: models.AttackersJSON{
+			{CharacterID: &pilotA, CorporationID: &corp},
+			{CharacterID: &pilotB, CorporationID: &corp, FinalBlow: true},
+		},
+	}
+	tracked := trackedSet{
+		models.EntityTypeCharacter:   {61: true},
+		models.EntityTypeCorporation: {98200001: true},
+		models.EntityTypeAlliance:    {},
+	}
+
+	links := killEntities([]models.Kill{kill}, tracked)
+	if len(links) != 2 {
+		t.Fatalf("links = %+v, want the tracked character and corporation", links)
+	}
+	for _, link := range links {
+		switch link.EntityType {
+		case models.EntityTypeCharacter:
+			if link.EntityID != 61 || link.CharacterID != 61 {
+				t.Errorf("character link = %+v", link)
+			}
+		case models.EntityTypeCorporation:
+			if link.CharacterID != 62 {
+				t.Errorf("corporation member = %d, want the final blow 62", link.CharacterID)
+			}
+		default:
+			t.Errorf("unexpected link %+v", link)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+// KillFilter is the set of criteria shared by every kill query. Zero values are ignored.
+// SovAllianceIDs and FWOccupierFactionIDs match the holder of the kill's system at the kill time.
+type KillFilter struct {
+	CharacterIDs         []int64
+	CorporationIDs       []int64
+	AllianceIDs          []int64
+	SystemIDs            []int
+	ConstellationIDs     []int
+	RegionIDs            []int
+	ShipTypeIDs          []int
+	SpaceClasses         []string
+	SovAllianceIDs       []int64
+	FWOccupierFactionIDs []int
+	StartTime            time.Time
+	EndTime              time.Time
+	MinValue             *float64
+	MaxValue             *float64
+	Solo                 *bool
+	NPC                  *bool
+	Awox                 *bool
+}
+
+// FilterError reports an invalid query parameter.
+type FilterError struct {
+	Param   string
+	Message string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("Invalid %s: %s", e.Param, e.Message)
+}
+
+// killFilterAliases maps legacy parameter names to their canonical form.
+var killFilterAliases = map[string]string{
+	"start_time": "startDate",
+	"end_time":   "endDate",
+	"system_id":  "systemID",
+	"region_id":  "regionID",
+}
+
+// ParseKillFilter builds a KillFilter from query parameters. ID parameters may be repeated
+// or comma separated. Dates accept YYYY-MM-DD or RFC3339; a date-only endDate includes the whole day.
+func ParseKillFilter(values url.Values) (KillFilter, error) {
+	var filter KillFilter
+	var err error
+
+	values = canonicalFilterValues(values)
+
+	if filter.CharacterIDs, err = parseInt64List(values, "characterID"); err != nil {
+		return filter, err
+	}
+	if filter.CorporationIDs, err = parseInt64List(values, "corporationID"); err != nil {
+		return filter, err
+	}
+	if filter.AllianceIDs, err = parseInt64List(values, "allianceID"); err != nil {
+		return filter, err
+	}
+	if filter.SystemIDs, err = parseIntList(values, "systemID"); err != nil {
+		return filter, err
+	}
+	if filter.ConstellationIDs, err = parseIntList(values, "constellationID"); err != nil {
+		return filter, err
+	}
+	if filter.RegionIDs, err = parseIntList(values, "regionID"); err != nil {
+		return filter, err
+	}
+	if filter.ShipTypeIDs, err = parseIntList(values, "shipTypeID"); err != nil {
+		return filter, err
+	}
+
+	if filter.SovAllianceIDs, err = parseInt64List(values, "sovAllianceID"); err != nil {
+		return filter, err
+	}
+	if filter.FWOccupierFactionIDs, err = parseIntList(values, "fwOccupierFactionID"); err != nil {
+		return filter, err
+	}
+	if filter.SpaceClasses, err = parseSpaceList(values, "space"); err != nil {
+		return filter, err
+	}
+
+	if filter.StartTime, err = parseFilterTime(values, "startDate", false); err != nil {
+		return filter, err
+	}
+	if filter.EndTime, err = parseFilterTime(values, "endDate", true); err != nil {
+		return filter, err
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && filter.StartTime.After(filter.EndTime) {
+		return filter, &FilterError{Param: "endDate", Message: "must not be before startDate"}
+	}
+
+	if filter.MinValue, err = parseFloatParam(values, "minValue"); err != nil {
+		return filter, err
+	}
+	if filter.MaxValue, err = parseFloatParam(values, "maxValue"); err != nil {
+		return filter, err
+	}
+	if filter.MinValue != nil && filter.MaxValue != nil && *filter.MinValue > *filter.MaxValue {
+		return filter, &FilterError{Param: "maxValue", Message: "must not be below minValue"}
+	}
+
+	if filter.Solo, err = parseBoolParam(values, "solo"); err != nil {
+		return filter, err
+	}
+	if filter.NPC, err = parseBoolParam(values, "npc"); err != nil {
+		return filter, err
+	}
+	if filter.Awox, err = parseBoolParam(values, "awox"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func canonicalFilterValues(values url.Values) url.Values {
+	canonical := make(url.Values, len(values))
+	for key, vals := range values {
+		if alias, ok := killFilterAliases[key]; ok {
+			key = alias
+		}
+		canonical[key] = append(canonical[key], vals...)
+	}
+	return canonical
+}
+
+func splitListParam(values url.Values, name string) []string {
+	var parts []string
+	for _, value := range values[name] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
+func parseInt64List(values url.Values, name string) ([]int64, error) {
+	var ids []int64
+	for _, part := range splitListParam(values, name) {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, &FilterError{Param: name, Message: fmt.Sprintf("%q is not a valid ID", part)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func parseIntList(values url.Values, name string) ([]int, error) {
+	var ids []int
+	for _, part := range splitListParam(values, name) {
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, &FilterError{Param: name, Message: fmt.Sprintf("%q is not a valid ID", part)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseSpaceList accepts space classes and the group names in models.SpaceGroups.
+func parseSpaceList(values url.Values, name string) ([]string, error) {
+	var classes []string
+	for _, part := range splitListParam(values, name) {
+		part = strings.ToLower(part)
+		if group, ok := models.SpaceGroups[part]; ok {
+			classes = append(classes, group...)
+			continue
+		}
+		if !models.IsSpaceClass(part) {
+			return nil, &FilterError{Param: name, Message: fmt.Sprintf("%q is not a known space class", part)}
+		}
+		classes = append(classes, part)
+	}
+	return classes, nil
+}
+
+func parseFilterTime(values url.Values, name string, endOfDay bool) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, &FilterError{Param: name, Message: "expected YYYY-MM-DD or RFC3339"}
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+func parseFloatParam(values url.Values, name string) (*float64, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return nil, &FilterError{Param: name, Message: "expected a non-negative number"}
+	}
+	return &f, nil
+}
+
+func parseBoolParam(values url.Values, name string) (*bool, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, &FilterError{Param: name, Message: "expected true or false"}
+	}
+	return &b, nil
+}
+
+// Scope applies the filter to a query over the kills table. Columns are qualified
+// so the scope can be combined with joins.
+func (f KillFilter) Scope() func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if len(f.CharacterIDs) > 0 {
+			query = query.Where(linkedSQL, models.EntityTypeCharacter, f.CharacterIDs)
+		}
+		if len(f.CorporationIDs) > 0 {
+			query = query.Where(linkedSQL, models.EntityTypeCorporation, f.CorporationIDs)
+		}
+		if len(f.AllianceIDs) > 0 {
+			query = query.Where(linkedSQL, models.EntityTypeAlliance, f.AllianceIDs)
+		}
+		query = f.locationScope(query)
+		if len(f.ShipTypeIDs) > 0 {
+			query = query.Where("kills.victim_ship_type_id IN ?", f.ShipTypeIDs)
+		}
+		if len(f.SovAllianceIDs) > 0 {
+			query = query.Where(`EXISTS (
+                SELECT 1 FROM sovereignty_histories sov
+                WHERE sov.system_id = kills.solar_system_id
+                    AND sov.alliance_id IN ?
+                    AND sov.valid_from <= kills.kill_time
+                    AND (sov.valid_to IS NULL OR sov.valid_to > kills.kill_time))`, f.SovAllianceIDs)
+		}
+		if len(f.FWOccupierFactionIDs) > 0 {
+			query = query.Where(`EXISTS (
+                SELECT 1 FROM faction_warfare_histories fw
+                WHERE fw.system_id = kills.solar_system_id
+                    AND fw.occupier_faction_id IN ?
+                    AND fw.valid_from <= kills.kill_time
+                    AND (fw.valid_to IS NULL OR fw.valid_to > kills.kill_time))`, f.FWOccupierFactionIDs)
+		}
+		if !f.StartTime.IsZero() {
+			query = query.Where("kills.kill_time >= ?", f.StartTime)
+		}
+		if !f.EndTime.IsZero() {
+			query = query.Where("kills.kill_time <= ?", f.EndTime)
+		}
+		if f.MinValue != nil {
+			query = query.Where("kills.total_value >= ?", *f.MinValue)
+		}
+		if f.MaxValue != nil {
+			query = query.Where("kills.total_value <= ?", *f.MaxValue)
+		}
+		if f.Solo != nil {
+			query = query.Where("kills.solo = ?", *f.Solo)
+		}
+		if f.NPC != nil {
+			query = query.Where("kills.npc = ?", *f.NPC)
+		}
+		if f.Awox != nil {
+			query = query.Where("kills.awox = ?", *f.Awox)
+		}
+		return query
+	}
+}
+
+// locationScope applies the system, constellation, region and space criteria to kills.solar_system_id.
+// Constellations, regions and space classes are resolved to systems from the universe cache.
+func (f KillFilter) locationScope(query *gorm.DB) *gorm.DB {
+	if len(f.SystemIDs) > 0 {
+		query = query.Where("kills.solar_system_id IN ?", f.SystemIDs)
+	}
+	if len(f.ConstellationIDs) == 0 && len(f.RegionIDs) == 0 && len(f.SpaceClasses) == 0 {
+		return query
+	}
+
+	constellations := intSet(f.ConstellationIDs)
+	regions := intSet(f.RegionIDs)
+	spaces := make(map[string]bool, len(f.SpaceClasses))
+	for _, space := range f.SpaceClasses {
+		spaces[space] = true
+	}
+	systemIDs, err := systemIDsWhere(func(system SystemLocation) bool {
+		return (len(constellations) == 0 || constellations[system.ConstellationID]) &&
+			(len(regions) == 0 || regions[system.RegionID]) &&
+			(len(spaces) == 0 || spaces[system.SpaceClass])
+	})
+	if err != nil {
+		query.AddError(fmt.Errorf("error resolving systems: %v", err))
+		return query
+	}
+	return query.Where("kills.solar_system_id IN ?", systemIDs)
+}
+
+func intSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+func TestKillFilterLocationsFromUniverseCache(t *testing.T) {
+	if err := UpsertRegions([]models.Region{{RegionID: 10000901}, {RegionID: 10000902}}); err != nil {
+		t.Fatal(err)
+	}
+	constellations := []models.Constellation{{ConstellationID: 20000901, RegionID: 10000901}, {ConstellationID: 20000902, RegionID: 10000902}}
+	if err := UpsertConstellations(constellations); err != nil {
+		t.Fatal(err)
+	}
+	systems := []models.System{
+		{SystemID: 30000901, ConstellationID: 20000901, SpaceClass: models.SpaceHighsec},
+		{SystemID: 30000902, ConstellationID: 20000901, SpaceClass: models.SpaceLowsec},
+		{SystemID: 30000903, ConstellationID: 20000902, SpaceClass: models.SpaceLowsec},
+	}
+	if err := UpsertSystems(systems); err != nil {
+		t.Fatal(err)
+	}
+	InvalidateUniverseCache()
+
+	killTime := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	kills := []models.Kill{
+		{KillmailID: 9901, SolarSystemID: 30000901, KillTime: killTime},
+		{KillmailID: 9902, SolarSystemID: 30000902, KillTime: killTime},
+		{KillmailID: 9903, SolarSystemID: 30000903, KillTime: killTime},
+	}
+	if err := UpsertKills(kills); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		filter KillFilter
+		want   int64
+	}{
+		{"region", KillFilter{RegionIDs: []int{10000901}}, 2},
+		{"constellation", KillFilter{ConstellationIDs: []int{20000902}}, 1},
+		{"space", KillFilter{SpaceClasses: []string{models.SpaceLowsec}}, 2},
+		{"region and space", KillFilter{RegionIDs: []int{10000901}, SpaceClasses: []string{models.SpaceLowsec}}, 1},
+		{"unknown region", KillFilter{RegionIDs: []int{10000999}}, 0},
+	}
+	for _, tc := range cases {
+		count, err := CountKills(tc.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if count != tc.want {
+			t.Errorf("%s filter matched %d kills, want %d", tc.name, count, tc.want)
+		}
+	}
+}
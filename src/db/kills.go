@@ -0,0 +1,245 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// KillSort is a keyset-paginable ordering of kills. Ties are broken by killmail_id.
+type KillSort struct {
+	Column string
+	Desc   bool
+}
+
+var KillSorts = map[string]KillSort{
+	"time_desc":  {Column: "kill_time", Desc: true},
+	"time_asc":   {Column: "kill_time", Desc: false},
+	"value_desc": {Column: "total_value", Desc: true},
+	"value_asc":  {Column: "total_value", Desc: false},
+}
+
+// KillCursor marks a position in a sorted kill listing. Backward cursors page towards the start.
+type KillCursor struct {
+	Sort       string `json:"s"`
+	Value      string `json:"v"`
+	KillmailID int64  `json:"id"`
+	Backward   bool   `json:"b,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("malformed cursor")
+
+func (c KillCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeKillCursor(encoded string) (*KillCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor KillCursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := KillSorts[cursor.Sort]; !ok {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func cursorFor(sortName string, kill models.Kill, backward bool) string {
+	cursor := KillCursor{Sort: sortName, KillmailID: kill.KillmailID, Backward: backward}
+	if KillSorts[sortName].Column == "kill_time" {
+		cursor.Value = kill.KillTime.Format(time.RFC3339Nano)
+	} else {
+		cursor.Value = strconv.FormatFloat(kill.TotalValue, 'g', -1, 64)
+	}
+	return cursor.Encode()
+}
+
+func (c KillCursor) keyValue() (interface{}, error) {
+	if KillSorts[c.Sort].Column == "kill_time" {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	}
+
+	v, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return v, nil
+}
+
+// ListKills returns one page of kills using keyset pagination on (sort column, killmail_id),
+// along with cursors for the following and preceding pages when they exist.
+func ListKills(filter KillFilter, sortName string, cursor *KillCursor, limit int) ([]models.Kill, string, string, error) {
+	if cursor != nil {
+		sortName = cursor.Sort
+	}
+	sort := KillSorts[sortName]
+
+	desc := sort.Desc
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		desc = !desc
+	}
+
+	query := DB.Model(&models.Kill{}).Scopes(filter.Scope())
+
+	if cursor != nil {
+		value, err := cursor.keyValue()
+		if err != nil {
+			return nil, "", "", err
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		query = query.Where("("+sort.Column+", killmail_id) "+op+" (?, ?)", value, cursor.KillmailID)
+	}
+
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+
+	var kills []models.Kill
+	err := query.
+		Order(sort.Column + direction).
+		Order("killmail_id" + direction).
+		Limit(limit + 1).
+		Find(&kills).Error
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	hasMore := len(kills) > limit
+	if hasMore {
+		kills = kills[:limit]
+	}
+	if backward {
+		for i, j := 0, len(kills)-1; i < j; i, j = i+1, j-1 {
+			kills[i], kills[j] = kills[j], kills[i]
+		}
+	}
+
+	if len(kills) == 0 {
+		return kills, "", "", nil
+	}
+
+	var next, prev string
+	if (!backward && hasMore) || (backward && cursor != nil) {
+		next = cursorFor(sortName, kills[len(kills)-1], false)
+	}
+	if (backward && hasMore) || (!backward && cursor != nil) {
+		prev = cursorFor(sortName, kills[0], true)
+	}
+
+	return kills, next, prev, nil
+}
+
+func CountKills(filter KillFilter) (int64, error) {
+	var count int64
+	err := DB.Model(&models.Kill{}).Scopes(filter.Scope()).Count(&count).Error
+	return count, err
+}
+
+// GetKills returns one page of kills matching the filter, newest first, with the total match count.
+func GetKills(filter KillFilter, page, pageSize int) ([]models.Kill, int64, error) {
+	var kills []models.Kill
+	var totalCount int64
+
+	query := DB.Model(&models.Kill{}).Scopes(filter.Scope())
+
+	err := query.Count(&totalCount).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.
+		Order("kills.kill_time DESC").
+		Order("kills.killmail_id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&kills).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return kills, totalCount, nil
+}
+
+// StreamKills calls fn for every kill matching the filter, newest first, reading from a
+// database cursor so the full result set is never held in memory.
+func StreamKills(filter KillFilter, fn func(*models.Kill) error) error {
+	rows, err := DB.Model(&models.Kill{}).
+		Scopes(filter.Scope()).
+		Order("kills.kill_time DESC").
+		Order("kills.killmail_id DESC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kill models.Kill
+		if err := DB.ScanRows(rows, &kill); err != nil {
+			return err
+		}
+		if err := fn(&kill); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetKillsIngestedAfter returns up to limit kills matching the filter that were stored after the
+// given kill, in the order they were stored. Ties within a batch are broken by killmail_id. When
+// involving is set, only kills one of those characters took part in are returned. Nothing is
+// returned for an unknown killmail ID, and kills stored before ingestion times were recorded
+// are never returned.
+func GetKillsIngestedAfter(killmailID int64, filter KillFilter, involving []int64, limit int) ([]models.Kill, error) {
+	var last struct {
+		IngestedAt *time.Time
+	}
+	result := DB.Model(&models.Kill{}).Select("ingested_at").Where("killmail_id = ?", killmailID).Limit(1).Scan(&last)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	query := DB.Model(&models.Kill{}).Scopes(filter.Scope())
+	if last.IngestedAt == nil {
+		query = query.Where("kills.ingested_at IS NOT NULL")
+	} else {
+		query = query.Where("kills.ingested_at > ? OR (kills.ingested_at = ? AND kills.killmail_id > ?)", *last.IngestedAt, *last.IngestedAt, killmailID)
+	}
+	if len(involving) > 0 {
+		query = query.Where("kills.character_id IN ? OR kills.victim_character_id IN ? OR "+attackerCharacterSQL(), involving, involving, involving)
+	}
+
+	var kills []models.Kill
+	err := query.Order("kills.ingested_at").Order("kills.killmail_id").Limit(limit).Find(&kills).Error
+	return kills, err
+}
+
+// attackerCharacterSQL matches kills with an attacker among the character IDs bound to it.
+func attackerCharacterSQL() string {
+	if IsPostgres() {
+		return "EXISTS (SELECT 1 FROM jsonb_array_elements(kills.attackers) AS attacker WHERE (attacker->>'character_id')::bigint IN ?)"
+	}
+	return "EXISTS (SELECT 1 FROM json_each(kills.attackers) AS attacker WHERE json_extract(attacker.value, '$.character_id') IN ?)"
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// localizedSources maps each localized kind to the table and ID column of its objects.
+var localizedSources = map[string]struct{ table, idColumn string }{
+	models.LocalizedRegion:        {"regions", "region_id"},
+	models.LocalizedConstellation: {"constellations", "constellation_id"},
+	models.LocalizedSystem:        {"systems", "system_id"},
+	models.LocalizedType:          {"esi_items", "type_id"},
+}
+
+func UpsertLocalizedName(name *models.LocalizedName) error {
+	return UpsertLocalizedNames([]models.LocalizedName{*name})
+}
+
+// GetLocalizedNames returns the names of the given objects in a language, keyed by ID.
+func GetLocalizedNames(kind string, ids []int, language string) (map[int]models.LocalizedName, error) {
+	names := make(map[int]models.LocalizedName, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	var rows []models.LocalizedName
+	err := DB.Where("kind = ? AND language = ? AND id IN ?", kind, language, ids).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		names[row.ID] = row
+	}
+	return names, nil
+}
+
+// GetUnlocalizedIDs returns the objects of a kind that have no name stored in the language.
+func GetUnlocalizedIDs(kind, language string) ([]int, error) {
+	source, ok := localizedSources[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown localized kind %q", kind)
+	}
+
+	var ids []int
+	err := DB.Table(source.table).
+		Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM localized_names WHERE kind = ? AND language = ? AND localized_names.id = %s.%s)", source.table, source.idColumn), kind, language).
+		Pluck(source.idColumn, &ids).Error
+	return ids, err
+}
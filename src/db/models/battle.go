@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Battle is a cluster of kills close together in time and space. Its summary columns are
+// recomputed from the member kills whenever the cluster changes. TotalValue is summed from the
+// kills when the battle is read, as their values change after they are assigned.
+type Battle struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	StartTime  time.Time `gorm:"index" json:"start_time"`
+	EndTime    time.Time `gorm:"index" json:"end_time"`
+	SystemIDs  IntArray  `gorm:"type:jsonb" json:"system_ids"`
+	KillCount  int       `gorm:"index" json:"kill_count"`
+	TotalValue float64   `gorm:"->;-:migration" json:"total_value"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BattleKill assigns a kill to the battle it belongs to. A kill is part of at most one battle.
+type BattleKill struct {
+	KillmailID int64 `gorm:"primaryKey;autoIncrement:false" json:"killmail_id"`
+	BattleID   uint  `gorm:"index" json:"battle_id"`
+}
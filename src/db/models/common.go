@@ -4,10 +4,15 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// PaginatedResponse is a page of a listing. Keyset-paginated listings carry cursors to the
+// next and previous pages instead of a page number, which are omitted at either end of the
+// listing; their totals are only filled in when requested.
 type PaginatedResponse struct {
 	Data       interface{} `json:"data"`
 	Page       int         `json:"page"`
 	PageSize   int         `json:"pageSize"`
 	TotalItems int         `json:"totalItems"`
 	TotalPages int         `json:"totalPages"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
 }
@@ -27,6 +27,10 @@ type Position struct {
 	Z float64 `json:"z"`
 }
 
+func (p Position) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
 func (p *Position) Scan(value interface{}) error {
 	bytes, ok := value.([]byte)
 	if !ok {
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// EntityName caches the name of a character, corporation, alliance or faction as resolved by ESI.
+type EntityName struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	Category  string    `gorm:"type:text;index" json:"category"`
+	Name      string    `gorm:"type:text" json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ImportProgress records how far the bulk importer got through one history file,
+// so interrupted imports resume where they stopped.
+type ImportProgress struct {
+	FileName       string     `gorm:"primaryKey;type:text" json:"file_name"`
+	TotalKills     int        `json:"total_kills"`
+	ProcessedKills int        `json:"processed_kills"`
+	ImportedKills  int        `json:"imported_kills"`
+	LastKillmailID int64      `json:"last_killmail_id"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
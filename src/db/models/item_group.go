@@ -0,0 +1,8 @@
+package models
+
+type ItemGroup struct {
+	GroupID    int    `gorm:"primaryKey;autoIncrement:false" json:"group_id"`
+	Name       string `gorm:"type:text" json:"name"`
+	CategoryID int    `gorm:"index" json:"category_id"`
+	Published  bool   `json:"published"`
+}
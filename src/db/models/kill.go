@@ -13,22 +13,29 @@ type Character struct {
 }
 
 type Kill struct {
-	KillmailID     int64         `json:"killmail_id" gorm:"primaryKey"`
-	CharacterID    int64         `json:"character_id"`
-	KillTime       time.Time     `json:"killmail_time"`
-	SolarSystemID  int           `json:"solar_system_id"`
-	LocationID     int64         `json:"locationID"`
-	Hash           string        `json:"hash"`
-	FittedValue    float64       `json:"fitted_value"`
-	DroppedValue   float64       `json:"dropped_value"`
-	DestroyedValue float64       `json:"destroyed_value"`
-	TotalValue     float64       `json:"total_value"`
-	Points         int           `json:"points"`
-	NPC            bool          `json:"npc"`
-	Solo           bool          `json:"solo"`
-	Awox           bool          `json:"awox"`
-	Victim         Victim        `json:"victim" gorm:"embedded;embeddedPrefix:victim_"`
-	Attackers      AttackersJSON `json:"attackers" gorm:"type:jsonb"`
+	KillmailID             int64         `json:"killmail_id" gorm:"primaryKey;index:idx_kills_time_id,priority:2;index:idx_kills_value_id,priority:2;index:idx_kills_ingested_id,priority:2"`
+	CharacterID            int64         `json:"character_id"`
+	CorporationID          int64         `json:"corporation_id" gorm:"index"`
+	AllianceID             int64         `json:"alliance_id" gorm:"index"`
+	KillTime               time.Time     `json:"killmail_time" gorm:"index:idx_kills_time_id,priority:1"`
+	SolarSystemID          int           `json:"solar_system_id"`
+	LocationID             int64         `json:"locationID"`
+	Hash                   string        `json:"hash"`
+	FittedValue            float64       `json:"fitted_value"`
+	DroppedValue           float64       `json:"dropped_value"`
+	DestroyedValue         float64       `json:"destroyed_value"`
+	TotalValue             float64       `json:"total_value" gorm:"index:idx_kills_value_id,priority:1"`
+	Points                 int           `json:"points"`
+	NPC                    bool          `json:"npc"`
+	Solo                   bool          `json:"solo"`
+	Awox                   bool          `json:"awox"`
+	ComputedDestroyedValue float64       `json:"computed_destroyed_value"`
+	ComputedDroppedValue   float64       `json:"computed_dropped_value"`
+	ComputedTotalValue     float64       `json:"computed_total_value"`
+	ValuedAt               *time.Time    `json:"valued_at,omitempty" gorm:"index"`
+	IngestedAt             *time.Time    `json:"ingested_at,omitempty" gorm:"index:idx_kills_ingested_id,priority:1"`
+	Victim                 Victim        `json:"victim" gorm:"embedded;embeddedPrefix:victim_"`
+	Attackers              AttackersJSON `json:"attackers" gorm:"type:jsonb"`
 }
 
 type AttackersJSON []Attacker
@@ -77,6 +84,64 @@ type Item struct {
 	Flag              int  `json:"flag"`
 }
 
+// ResolveAffiliation attributes the kill to the tracked attacker belonging to the given entity,
+// preferring the final blow, and records that attacker's character, corporation and alliance.
+func (k *Kill) ResolveAffiliation(entityType string, entityID int64) {
+	var match *Attacker
+	for i := range k.Attackers {
+		a := &k.Attackers[i]
+		if !a.BelongsTo(entityType, entityID) {
+			continue
+		}
+		if match == nil || (a.FinalBlow && !match.FinalBlow) {
+			match = a
+		}
+	}
+	if match == nil {
+		return
+	}
+
+	if match.CharacterID != nil {
+		k.CharacterID = int64(*match.CharacterID)
+	}
+	if match.CorporationID != nil {
+		k.CorporationID = int64(*match.CorporationID)
+	}
+	if match.AllianceID != nil {
+		k.AllianceID = int64(*match.AllianceID)
+	}
+}
+
+// InvolvesCharacter reports whether the character is the tracked character, the victim or one of the attackers.
+func (k *Kill) InvolvesCharacter(characterID int64) bool {
+	if k.CharacterID == characterID {
+		return true
+	}
+	if k.Victim.CharacterID != nil && int64(*k.Victim.CharacterID) == characterID {
+		return true
+	}
+	for _, attacker := range k.Attackers {
+		if attacker.BelongsTo(EntityTypeCharacter, characterID) {
+			return true
+		}
+	}
+	return false
+}
+
+// BelongsTo reports whether the attacker is the given character or a member of the given corporation or alliance.
+func (a Attacker) BelongsTo(entityType string, entityID int64) bool {
+	var id *int
+	switch entityType {
+	case EntityTypeCharacter:
+		id = a.CharacterID
+	case EntityTypeCorporation:
+		id = a.CorporationID
+	case EntityTypeAlliance:
+		id = a.AllianceID
+	}
+	return id != nil && int64(*id) == entityID
+}
+
 func (a Attacker) Value() (driver.Value, error) {
 	return json.Marshal(a)
 }
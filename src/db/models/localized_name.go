@@ -0,0 +1,19 @@
+package models
+
+// Kinds of universe objects with localized names.
+const (
+	LocalizedRegion        = "region"
+	LocalizedConstellation = "constellation"
+	LocalizedSystem        = "system"
+	LocalizedType          = "type"
+)
+
+// LocalizedName is the name and description of a universe object in a language other than
+// English, which is stored on the object's own table.
+type LocalizedName struct {
+	Kind        string `gorm:"primaryKey;type:text" json:"kind"`
+	ID          int    `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	Language    string `gorm:"primaryKey;type:text" json:"language"`
+	Name        string `gorm:"type:text" json:"name"`
+	Description string `gorm:"type:text" json:"description,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	WebhookFormatGeneric = "generic"
+	WebhookFormatDiscord = "discord"
+	WebhookFormatSlack   = "slack"
+)
+
+// NotificationRule describes which newly ingested kills are pushed to a webhook.
+// Empty criteria match every kill.
+type NotificationRule struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	Name               string    `gorm:"type:text" json:"name" binding:"required"`
+	WebhookURL         string    `gorm:"type:text" json:"webhook_url" binding:"required,url"`
+	Format             string    `gorm:"type:text" json:"format"`
+	Enabled            bool      `json:"enabled"`
+	MinTotalValue      float64   `json:"min_total_value"`
+	VictimShipGroupIDs IntArray  `gorm:"type:jsonb" json:"victim_ship_group_ids"`
+	RegionIDs          IntArray  `gorm:"type:jsonb" json:"region_ids"`
+	SystemIDs          IntArray  `gorm:"type:jsonb" json:"system_ids"`
+	CharacterIDs       IntArray  `gorm:"type:jsonb" json:"character_ids"`
+	SoloOnly           bool      `json:"solo_only"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TypePrice is a daily price observation for a type. RegionID 0 holds ESI's global
+// adjusted/average prices; other regions hold market history averages.
+type TypePrice struct {
+	TypeID        int       `gorm:"primaryKey;autoIncrement:false" json:"type_id"`
+	RegionID      int       `gorm:"primaryKey;autoIncrement:false" json:"region_id"`
+	Date          time.Time `gorm:"primaryKey;type:date" json:"date"`
+	AdjustedPrice float64   `json:"adjusted_price"`
+	AveragePrice  float64   `json:"average_price"`
+	Highest       float64   `json:"highest"`
+	Lowest        float64   `json:"lowest"`
+	Volume        int64     `json:"volume"`
+	OrderCount    int64     `json:"order_count"`
+}
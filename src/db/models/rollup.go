@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// DailyCharacterStats rolls up the kills linked to each character per UTC day and system.
+type DailyCharacterStats struct {
+	Day           time.Time `gorm:"primaryKey;type:date"`
+	CharacterID   int64     `gorm:"primaryKey;autoIncrement:false"`
+	SolarSystemID int       `gorm:"primaryKey;autoIncrement:false"`
+	KillCount     int
+	TotalISK      float64 `gorm:"column:total_isk"`
+}
+
+func (DailyCharacterStats) TableName() string {
+	return "daily_character_stats"
+}
+
+// DailySystemStats rolls up all stored kills per UTC day and system.
+type DailySystemStats struct {
+	Day           time.Time `gorm:"primaryKey;type:date"`
+	SolarSystemID int       `gorm:"primaryKey;autoIncrement:false"`
+	KillCount     int
+	TotalISK      float64 `gorm:"column:total_isk"`
+}
+
+func (DailySystemStats) TableName() string {
+	return "daily_system_stats"
+}
+
+// DailyShipTypeStats rolls up all stored kills per UTC day and victim ship type.
+type DailyShipTypeStats struct {
+	Day        time.Time `gorm:"primaryKey;type:date"`
+	ShipTypeID int       `gorm:"primaryKey;autoIncrement:false"`
+	KillCount  int
+	TotalISK   float64 `gorm:"column:total_isk"`
+}
+
+func (DailyShipTypeStats) TableName() string {
+	return "daily_ship_type_stats"
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// SovereigntyHistory records who held sovereignty over a system and when. The current holder
+// is the row with no ValidTo. Rows start at the first ingestion that saw the holder.
+type SovereigntyHistory struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SystemID      int        `gorm:"index:idx_sov_history_system,priority:1" json:"system_id"`
+	AllianceID    int64      `gorm:"index" json:"alliance_id,omitempty"`
+	CorporationID int64      `json:"corporation_id,omitempty"`
+	FactionID     int        `json:"faction_id,omitempty"`
+	ValidFrom     time.Time  `gorm:"index:idx_sov_history_system,priority:2" json:"valid_from"`
+	ValidTo       *time.Time `json:"valid_to,omitempty"`
+}
+
+// SovereigntyStructureHistory records which alliance held a sovereignty structure and when.
+// The occupancy level and vulnerability window are kept current on the open row.
+type SovereigntyStructureHistory struct {
+	ID                          uint       `gorm:"primaryKey" json:"id"`
+	StructureID                 int64      `gorm:"index" json:"structure_id"`
+	StructureTypeID             int        `json:"structure_type_id"`
+	SystemID                    int        `gorm:"index" json:"system_id"`
+	AllianceID                  int64      `gorm:"index" json:"alliance_id"`
+	VulnerabilityOccupancyLevel float64    `json:"vulnerability_occupancy_level"`
+	VulnerableStartTime         *time.Time `json:"vulnerable_start_time,omitempty"`
+	VulnerableEndTime           *time.Time `json:"vulnerable_end_time,omitempty"`
+	ValidFrom                   time.Time  `json:"valid_from"`
+	ValidTo                     *time.Time `json:"valid_to,omitempty"`
+}
+
+// FactionWarfareHistory records the owning and occupying faction of a warzone system and when.
+// Contest status and victory points are kept current on the open row.
+type FactionWarfareHistory struct {
+	ID                     uint       `gorm:"primaryKey" json:"id"`
+	SystemID               int        `gorm:"index:idx_fw_history_system,priority:1" json:"system_id"`
+	OwnerFactionID         int        `json:"owner_faction_id"`
+	OccupierFactionID      int        `gorm:"index" json:"occupier_faction_id"`
+	Contested              string     `gorm:"type:text" json:"contested"`
+	VictoryPoints          int        `json:"victory_points"`
+	VictoryPointsThreshold int        `json:"victory_points_threshold"`
+	ValidFrom              time.Time  `gorm:"index:idx_fw_history_system,priority:2" json:"valid_from"`
+	ValidTo                *time.Time `json:"valid_to,omitempty"`
+}
@@ -0,0 +1,89 @@
+package models
+
+// Space classes of solar systems. Wormhole classes follow the region the system is in.
+const (
+	SpaceHighsec   = "highsec"
+	SpaceLowsec    = "lowsec"
+	SpaceNullsec   = "nullsec"
+	SpacePochven   = "pochven"
+	SpaceC1        = "c1"
+	SpaceC2        = "c2"
+	SpaceC3        = "c3"
+	SpaceC4        = "c4"
+	SpaceC5        = "c5"
+	SpaceC6        = "c6"
+	SpaceThera     = "thera"
+	SpaceShattered = "shattered"
+	SpaceDrifter   = "drifter"
+	SpaceAbyssal   = "abyssal"
+)
+
+// SpaceGroups name sets of space classes accepted wherever a single class is.
+var SpaceGroups = map[string][]string{
+	"kspace":   {SpaceHighsec, SpaceLowsec, SpaceNullsec, SpacePochven},
+	"wormhole": {SpaceC1, SpaceC2, SpaceC3, SpaceC4, SpaceC5, SpaceC6, SpaceThera, SpaceShattered, SpaceDrifter},
+}
+
+const (
+	regionPochven        = 10000070
+	regionWormholeFirst  = 11000001
+	regionWormholeLast   = 11000033
+	regionAbyssalFirst   = 12000000
+	regionAbyssalLast    = 14999999
+	regionTheraWormhole  = 11000031
+	regionShattered      = 11000032
+	regionDrifterSpace   = 11000033
+	highsecMinimumStatus = 0.45
+)
+
+// wormholeClasses maps the last wormhole region ID of each class to that class.
+var wormholeClasses = []struct {
+	lastRegion int
+	class      string
+}{
+	{11000003, SpaceC1},
+	{11000008, SpaceC2},
+	{11000015, SpaceC3},
+	{11000023, SpaceC4},
+	{11000029, SpaceC5},
+	{11000030, SpaceC6},
+}
+
+// ClassifySpace derives the space class of a system from its region and security status.
+// Known space is split by displayed security, which rounds any positive status up to at least 0.1.
+func ClassifySpace(regionID int, securityStatus float64) string {
+	switch {
+	case regionID == regionPochven:
+		return SpacePochven
+	case regionID == regionTheraWormhole:
+		return SpaceThera
+	case regionID == regionShattered:
+		return SpaceShattered
+	case regionID == regionDrifterSpace:
+		return SpaceDrifter
+	case regionID >= regionWormholeFirst && regionID <= regionWormholeLast:
+		for _, c := range wormholeClasses {
+			if regionID <= c.lastRegion {
+				return c.class
+			}
+		}
+	case regionID >= regionAbyssalFirst && regionID <= regionAbyssalLast:
+		return SpaceAbyssal
+	case securityStatus >= highsecMinimumStatus:
+		return SpaceHighsec
+	case securityStatus > 0:
+		return SpaceLowsec
+	}
+	return SpaceNullsec
+}
+
+// IsSpaceClass reports whether s is a known space class.
+func IsSpaceClass(s string) bool {
+	switch s {
+	case SpaceHighsec, SpaceLowsec, SpaceNullsec, SpacePochven,
+		SpaceC1, SpaceC2, SpaceC3, SpaceC4, SpaceC5, SpaceC6,
+		SpaceThera, SpaceShattered, SpaceDrifter, SpaceAbyssal:
+		return true
+	}
+	return false
+}
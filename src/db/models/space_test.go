@@ -0,0 +1,57 @@
+package models
+
+import "testing"
+
+func TestClassifySpace(t *testing.T) {
+	cases := []struct {
+		name           string
+		regionID       int
+		securityStatus float64
+		want           string
+	}{
+		{"Jita", 10000002, 0.9459, SpaceHighsec},
+		{"Tama", 10000016, 0.3, SpaceLowsec},
+		{"1DQ1-A", 10000060, -0.38, SpaceNullsec},
+		{"Ahtila", regionPochven, -0.02, SpacePochven},
+		{"C1", 11000001, -0.99, SpaceC1},
+		{"C2", 11000004, -0.99, SpaceC2},
+		{"C3", 11000009, -0.99, SpaceC3},
+		{"C4", 11000016, -0.99, SpaceC4},
+		{"C5", 11000024, -0.99, SpaceC5},
+		{"C6", 11000030, -0.99, SpaceC6},
+		{"Thera", regionTheraWormhole, -0.99, SpaceThera},
+		{"shattered", regionShattered, -0.99, SpaceShattered},
+		{"drifter", regionDrifterSpace, -0.99, SpaceDrifter},
+		{"abyssal", 12000001, -1, SpaceAbyssal},
+		{"highsec boundary", 10000002, 0.45, SpaceHighsec},
+		{"below highsec boundary", 10000002, 0.4499, SpaceLowsec},
+		{"lowsec boundary", 10000016, 0.05, SpaceLowsec},
+		{"barely positive", 10000016, 0.0001, SpaceLowsec},
+		{"zero security", 10000060, 0.0, SpaceNullsec},
+	}
+
+	for _, tc := range cases {
+		if got := ClassifySpace(tc.regionID, tc.securityStatus); got != tc.want {
+			t.Errorf("%s: ClassifySpace(%d, %v) = %q, want %q", tc.name, tc.regionID, tc.securityStatus, got, tc.want)
+		}
+	}
+}
+
+func TestIsSpaceClass(t *testing.T) {
+	classes := []string{
+		SpaceHighsec, SpaceLowsec, SpaceNullsec, SpacePochven,
+		SpaceC1, SpaceC2, SpaceC3, SpaceC4, SpaceC5, SpaceC6,
+		SpaceThera, SpaceShattered, SpaceDrifter, SpaceAbyssal,
+	}
+	for _, class := range classes {
+		if !IsSpaceClass(class) {
+			t.Errorf("IsSpaceClass(%q) = false, want true", class)
+		}
+	}
+
+	for _, s := range []string{"", "kspace", "wormhole", "Highsec", "c7"} {
+		if IsSpaceClass(s) {
+			t.Errorf("IsSpaceClass(%q) = true, want false", s)
+		}
+	}
+}
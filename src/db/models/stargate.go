@@ -0,0 +1,13 @@
+package models
+
+type StargateDestination struct {
+	StargateID int `json:"stargate_id"`
+	SystemID   int `json:"system_id" gorm:"index"`
+}
+
+type Stargate struct {
+	StargateID  int                 `gorm:"primaryKey;autoIncrement:false" json:"stargate_id"`
+	Name        string              `gorm:"type:text" json:"name"`
+	SystemID    int                 `gorm:"index" json:"system_id"`
+	Destination StargateDestination `gorm:"embedded;embeddedPrefix:destination_" json:"destination"`
+}
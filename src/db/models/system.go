@@ -6,6 +6,7 @@ type System struct {
 	Name            string   `gorm:"type:text" json:"name"`
 	SecurityClass   string   `gorm:"type:text" json:"security_class"`
 	SecurityStatus  float64  `json:"security_status"`
+	SpaceClass      string   `gorm:"type:text;index" json:"space_class"`
 	StarID          int      `json:"star_id"`
 	Planets         IntArray `gorm:"type:jsonb" json:"planets"`
 	Stargates       IntArray `gorm:"type:jsonb" json:"stargates"`
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	EntityTypeCharacter   = "character"
+	EntityTypeCorporation = "corporation"
+	EntityTypeAlliance    = "alliance"
+)
+
+// TrackedEntity is a corporation or alliance whose kills are fetched as a whole.
+// Individual characters are still tracked through the characters table.
+type TrackedEntity struct {
+	EntityType string    `gorm:"primaryKey;type:text" json:"entity_type"`
+	EntityID   int64     `gorm:"primaryKey;autoIncrement:false" json:"entity_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ZKillboardModifier returns the zKillboard API modifier used to fetch kills for the entity type.
+func ZKillboardModifier(entityType string) string {
+	switch entityType {
+	case EntityTypeCorporation:
+		return "corporationID"
+	case EntityTypeAlliance:
+		return "allianceID"
+	default:
+		return "characterID"
+	}
+}
+
+// KillEntity links a kill to a character, corporation or alliance it counts for: the one it is
+// attributed to and every tracked one among its attackers. A kill involving members of several
+// tracked entities therefore counts for each of them. CharacterID is the entity's member on the
+// kill, preferring the final blow.
+type KillEntity struct {
+	EntityType  string    `gorm:"primaryKey;type:text;index:idx_kill_entities_time,priority:1" json:"entity_type"`
+	EntityID    int64     `gorm:"primaryKey;autoIncrement:false;index:idx_kill_entities_time,priority:2" json:"entity_id"`
+	KillmailID  int64     `gorm:"primaryKey;autoIncrement:false;index" json:"killmail_id"`
+	KillTime    time.Time `gorm:"index:idx_kill_entities_time,priority:3" json:"kill_time"`
+	CharacterID int64     `json:"character_id"`
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm/clause"
+)
+
+func GetEntityNames(ids []int64) (map[int64]models.EntityName, error) {
+	names := make(map[int64]models.EntityName, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	var rows []models.EntityName
+	err := DB.Where("id IN ?", ids).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range rows {
+		names[name.ID] = name
+	}
+	return names, nil
+}
+
+func UpsertEntityNames(names []models.EntityName) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"category", "name", "updated_at"}),
+	}).Create(&names).Error
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+func CreateNotificationRule(rule *models.NotificationRule) error {
+	return DB.Create(rule).Error
+}
+
+func GetAllNotificationRules() ([]models.NotificationRule, error) {
+	var rules []models.NotificationRule
+	err := DB.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func GetEnabledNotificationRules() ([]models.NotificationRule, error) {
+	var rules []models.NotificationRule
+	err := DB.Where("enabled = ?", true).Find(&rules).Error
+	return rules, err
+}
+
+func GetNotificationRule(id uint) (*models.NotificationRule, error) {
+	var rule models.NotificationRule
+	err := DB.First(&rule, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &rule, err
+}
+
+func UpdateNotificationRule(rule *models.NotificationRule) error {
+	return DB.Save(rule).Error
+}
+
+func DeleteNotificationRule(id uint) error {
+	return DB.Delete(&models.NotificationRule{}, id).Error
+}
@@ -0,0 +1,274 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+// Kills are range partitioned on kill_time by UTC month. Partitions are named kills_yYYYYmMM
+// and are created on demand before kills of a new month are written.
+
+// KillPartition is one monthly partition of kills, covering [From, To).
+type KillPartition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// retainedSince holds the Unix time of the oldest month the retention policy keeps; 0 keeps
+// every month.
+var retainedSince atomic.Int64
+
+// SetKillRetention sets the oldest month kept by the retention policy. Kills of earlier months
+// are archived, so writes skip them rather than recreating their partitions; a zero time
+// keeps every month.
+func SetKillRetention(since time.Time) {
+	if since.IsZero() {
+		retainedSince.Store(0)
+		return
+	}
+	retainedSince.Store(monthStart(since).Unix())
+}
+
+// withinRetention returns the kills of months the retention policy keeps, logging the others.
+func withinRetention(kills []models.Kill) []models.Kill {
+	since := retainedSince.Load()
+	if since == 0 {
+		return kills
+	}
+	cutoff := time.Unix(since, 0)
+
+	retained := make([]models.Kill, 0, len(kills))
+	for i := range kills {
+		if kills[i].KillTime.Before(cutoff) {
+			continue
+		}
+		retained = append(retained, kills[i])
+	}
+	if skipped := len(kills) - len(retained); skipped > 0 {
+		log.Printf("Skipped %d kills before the retention cutoff %s", skipped, cutoff.UTC().Format("2006-01"))
+		return retained
+	}
+	return kills
+}
+
+// knownPartitions remembers the partitions created or seen by this process. Another process
+// may drop one of them, so a write that finds no partition forgets them and tries again.
+var knownPartitions sync.Map
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func partitionFor(t time.Time) KillPartition {
+	from := monthStart(t)
+	return KillPartition{
+		Name: fmt.Sprintf("kills_y%04dm%02d", from.Year(), from.Month()),
+		From: from,
+		To:   from.AddDate(0, 1, 0),
+	}
+}
+
+func createKillPartition(tx *gorm.DB, p KillPartition) error {
+	if _, ok := knownPartitions.Load(p.Name); ok {
+		return nil
+	}
+	err := tx.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF kills FOR VALUES FROM ('%s') TO ('%s')",
+		p.Name, p.From.Format("2006-01-02 15:04:05+00"), p.To.Format("2006-01-02 15:04:05+00"))).Error
+	if err != nil {
+		return fmt.Errorf("error creating partition %s: %v", p.Name, err)
+	}
+	knownPartitions.Store(p.Name, struct{}{})
+	return nil
+}
+
+// ensureKillPartitions creates the partitions the given kills will be written to.
+func ensureKillPartitions(kills []models.Kill) error {
+	for i := range kills {
+		if err := createKillPartition(DB, partitionFor(kills[i].KillTime)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forgetKillPartitions drops the partitions of the given kills from knownPartitions, so the
+// next ensureKillPartitions checks them again.
+func forgetKillPartitions(kills []models.Kill) {
+	for i := range kills {
+		knownPartitions.Delete(partitionFor(kills[i].KillTime).Name)
+	}
+}
+
+// checkViolation is the SQLSTATE Postgres reports for a row that fits no partition.
+const checkViolation = "23514"
+
+// isMissingPartition reports whether err is Postgres refusing a row no partition of kills
+// accepts.
+func isMissingPartition(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == checkViolation && strings.Contains(pgErr.Message, "no partition")
+}
+
+// EnsureKillPartitions creates the partitions of every month from the current one to months
+// ahead, so ingestion never waits on DDL at the turn of a month.
+func EnsureKillPartitions(months int) error {
+	if err := requirePostgres(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := 0; i <= months; i++ {
+		if err := createKillPartition(DB, partitionFor(now.AddDate(0, i, 0))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PartitionKills converts a plain kills table, as created by AutoMigrate or left by earlier
+// versions, into a partitioned one. The primary key becomes (killmail_id, kill_time) because
+// Postgres requires unique keys to include the partition key.
+func PartitionKills() error {
+	var kind string
+	if err := DB.Raw("SELECT relkind FROM pg_class WHERE oid = 'kills'::regclass").Row().Scan(&kind); err != nil {
+		return err
+	}
+	if kind == "p" {
+		return nil
+	}
+
+	log.Println("Converting kills to a table partitioned by month")
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE kills RENAME TO kills_unpartitioned",
+			"ALTER TABLE kills_unpartitioned RENAME CONSTRAINT kills_pkey TO kills_unpartitioned_pkey",
+			"CREATE TABLE kills (LIKE kills_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (kill_time)",
+			"ALTER TABLE kills ADD CONSTRAINT kills_pkey PRIMARY KEY (killmail_id, kill_time)",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("error running %q: %v", statement, err)
+			}
+		}
+
+		var months []struct{ Month time.Time }
+		err := tx.Raw("SELECT DISTINCT date_trunc('month', kill_time AT TIME ZONE 'UTC') AS month FROM kills_unpartitioned").Scan(&months).Error
+		if err != nil {
+			return err
+		}
+		for _, m := range months {
+			if err := createKillPartition(tx, partitionFor(m.Month)); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec("INSERT INTO kills SELECT * FROM kills_unpartitioned").Error; err != nil {
+			return fmt.Errorf("error copying kills: %v", err)
+		}
+
+		// A serial killmail_id default points at a sequence owned by the old table.
+		var sequence sql.NullString
+		if err := tx.Raw("SELECT pg_get_serial_sequence('kills_unpartitioned', 'killmail_id')").Row().Scan(&sequence); err != nil {
+			return err
+		}
+		if sequence.Valid {
+			if err := tx.Exec(fmt.Sprintf("ALTER SEQUENCE %s OWNED BY kills.killmail_id", sequence.String)).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec("DROP TABLE kills_unpartitioned").Error
+	})
+	if err != nil {
+		knownPartitions.Range(func(key, _ any) bool {
+			knownPartitions.Delete(key)
+			return true
+		})
+		return err
+	}
+
+	// Recreate the model's indexes, which were dropped with the old table.
+	return DB.AutoMigrate(&models.Kill{})
+}
+
+// GetKillPartitions lists the monthly partitions of kills, oldest first.
+func GetKillPartitions() ([]KillPartition, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err := DB.Raw(`
+        SELECT child.relname FROM pg_inherits
+        JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+        WHERE pg_inherits.inhparent = 'kills'::regclass`).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []KillPartition
+	for _, name := range names {
+		var year, month int
+		if _, err := fmt.Sscanf(name, "kills_y%04dm%02d", &year, &month); err != nil {
+			continue
+		}
+		partitions = append(partitions, partitionFor(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)))
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].From.Before(partitions[j].From) })
+	return partitions, nil
+}
+
+// StreamPartitionKills calls fn for each kill stored in a partition.
+func StreamPartitionKills(p KillPartition, fn func(*models.Kill) error) error {
+	rows, err := DB.Table(p.Name).Order("kill_time, killmail_id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kill models.Kill
+		if err := DB.ScanRows(rows, &kill); err != nil {
+			return err
+		}
+		if err := fn(&kill); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DropKillPartition detaches a partition from kills and drops it. Rollups of its days are kept,
+// as are the links of its kills, which hold the fetch watermarks of their entities.
+func DropKillPartition(p KillPartition) error {
+	if err := requirePostgres(); err != nil {
+		return err
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE kills DETACH PARTITION %s", p.Name)).Error; err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("DROP TABLE %s", p.Name)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	knownPartitions.Delete(p.Name)
+	notifyKillsChanged()
+	return nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm/clause"
+)
+
+func UpsertTypePrices(prices []models.TypePrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "type_id"}, {Name: "region_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"adjusted_price", "average_price", "highest", "lowest", "volume", "order_count"}),
+	}).CreateInBatches(prices, 1000).Error
+}
+
+func GetTypePriceHistory(typeID, regionID int, startTime, endTime time.Time) ([]models.TypePrice, error) {
+	query := DB.Where("type_id = ? AND region_id = ?", typeID, regionID)
+	if !startTime.IsZero() {
+		query = query.Where("date >= ?", startTime)
+	}
+	if !endTime.IsZero() {
+		query = query.Where("date <= ?", endTime)
+	}
+
+	var prices []models.TypePrice
+	err := query.Order("date").Find(&prices).Error
+	return prices, err
+}
+
+// GetPricesAt returns the price of each type closest to the given date. Regional market history
+// is preferred over ESI's global prices when both are equally close.
+func GetPricesAt(typeIDs []int, date time.Time) (map[int]float64, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[int]float64, len(typeIDs))
+	if len(typeIDs) == 0 {
+		return prices, nil
+	}
+
+	var rows []struct {
+		TypeID int
+		Price  float64
+	}
+	err := DB.Raw(`
+        SELECT DISTINCT ON (type_id) type_id, COALESCE(NULLIF(average_price, 0), adjusted_price) AS price
+        FROM type_prices
+        WHERE type_id IN ?
+        ORDER BY type_id, ABS(date - ?::date), (region_id <> 0) DESC
+    `, typeIDs, date.Format("2006-01-02")).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		prices[row.TypeID] = row.Price
+	}
+	return prices, nil
+}
+
+// GetKilledTypeIDs returns every ship and item type that appears on a stored victim.
+func GetKilledTypeIDs() ([]int, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	var typeIDs []int
+	err := DB.Raw(`
+        SELECT victim_ship_type_id FROM kills WHERE victim_ship_type_id <> 0
+        UNION
+        SELECT DISTINCT (item->>'item_type_id')::int
+        FROM kills CROSS JOIN LATERAL jsonb_array_elements(kills.victim_items) item
+        WHERE jsonb_typeof(kills.victim_items) = 'array'
+    `).Scan(&typeIDs).Error
+	return typeIDs, err
+}
+
+// GetUnvaluedKills returns kills that have not been valued, with a killmail ID above afterID.
+func GetUnvaluedKills(afterID int64, limit int) ([]models.Kill, error) {
+	var kills []models.Kill
+	err := DB.Where("valued_at IS NULL AND killmail_id > ?", afterID).Order("killmail_id").Limit(limit).Find(&kills).Error
+	return kills, err
+}
+
+func UpdateKillValuation(kill *models.Kill) error {
+	return DB.Model(&models.Kill{}).Where("killmail_id = ?", kill.KillmailID).Updates(map[string]interface{}{
+		"computed_destroyed_value": kill.ComputedDestroyedValue,
+		"computed_dropped_value":   kill.ComputedDroppedValue,
+		"computed_total_value":     kill.ComputedTotalValue,
+		"valued_at":                kill.ValuedAt,
+	}).Error
+}
+
+// ResetKillValuations marks the matching kills for revaluation.
+func ResetKillValuations(filter KillFilter) (int64, error) {
+	if err := requirePostgres(); err != nil {
+		return 0, err
+	}
+
+	result := DB.Model(&models.Kill{}).Scopes(filter.Scope()).Update("valued_at", nil)
+	return result.RowsAffected, result.Error
+}
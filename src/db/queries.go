@@ -1,192 +1,145 @@
 package db
 
 import (
-	"time"
+	"errors"
+	"log"
 
 	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
 )
 
 func GetConstellation(id int) (*models.Constellation, error) {
-	var constellation models.Constellation
-	result := DB.First(&constellation, id)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return &constellation, nil
+	return store.GetConstellation(id)
 }
 
-func GetConstellationsByRegionID(regionID int) ([]models.Constellation, error) {
-	var constellations []models.Constellation
-	result := DB.Where("region_id = ?", regionID).Find(&constellations)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return constellations, nil
+func GetRegion(id int) (*models.Region, error) {
+	return store.GetRegion(id)
 }
 
 func GetSystem(id int) (*models.System, error) {
-	var system models.System
-	result := DB.First(&system, id)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return &system, nil
+	return store.GetSystem(id)
 }
 
-func GetSystemsByRegionID(regionID int) ([]models.System, error) {
-	var systems []models.System
-	result := DB.Where("region_id = ?", regionID).Find(&systems)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return systems, nil
-}
-
-func GetKillsForCharacterWithFilters(characterID int64, page, pageSize, regionID int, startDate, endDate string) ([]models.Kill, error) {
+func GetCharacterKillmails(filter KillFilter) ([]models.Kill, error) {
 	var kills []models.Kill
-	query := DB.Where("character_id = ?", characterID)
-
-	if regionID != 0 {
-		query = query.Where("solar_system_id IN (SELECT system_id FROM systems WHERE region_id = ?)", regionID)
-	}
-
-	if startDate != "" {
-		startTime, _ := time.Parse("2006-01-02", startDate)
-		query = query.Where("kill_time >= ?", startTime)
-	}
-
-	if endDate != "" {
-		endTime, _ := time.Parse("2006-01-02", endDate)
-		query = query.Where("kill_time <= ?", endTime)
-	}
-
-	result := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&kills)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return kills, nil
+	err := DB.Model(&models.Kill{}).Scopes(filter.Scope()).Order("kills.kill_time DESC").Find(&kills).Error
+	return kills, err
 }
 
-func GetTotalKillsForCharacterWithFilters(characterID int64, regionID int, startDate, endDate string) (int64, error) {
-	var count int64
-	query := DB.Model(&models.Kill{}).Where("character_id = ?", characterID)
-
-	if regionID != 0 {
-		query = query.Where("solar_system_id IN (SELECT system_id FROM systems WHERE region_id = ?)", regionID)
-	}
-
-	if startDate != "" {
-		startTime, _ := time.Parse("2006-01-02", startDate)
-		query = query.Where("kill_time >= ?", startTime)
-	}
-
-	if endDate != "" {
-		endTime, _ := time.Parse("2006-01-02", endDate)
-		query = query.Where("kill_time <= ?", endTime)
-	}
-
-	result := query.Count(&count)
-	if result.Error != nil {
-		return 0, result.Error
-	}
-	return count, nil
+type CharacterStats struct {
+	CharacterID int64   `json:"character_id" parquet:"character_id"`
+	Space       string  `json:"space,omitempty" parquet:"space,optional"`
+	KillCount   int     `json:"kill_count" parquet:"kill_count"`
+	TotalISK    float64 `json:"total_isk" parquet:"total_isk"`
 }
 
-func GetKillsByRegion(regionID int, page, pageSize int, startDate, endDate string) ([]models.Kill, int64, error) {
-	var kills []models.Kill
-	var totalCount int64
-
-	query := DB.Table("kills").
-		Joins("JOIN systems ON kills.solar_system_id = systems.system_id").
-		Joins("JOIN constellations ON systems.constellation_id = constellations.constellation_id").
-		Where("constellations.region_id = ?", regionID)
+// spaceColumn is the space class of a kill's system, for stats broken down by space.
+const spaceColumn = "COALESCE(systems.space_class, '')"
 
-	if startDate != "" {
-		query = query.Where("kills.kill_time >= ?", startDate)
-	}
-	if endDate != "" {
-		query = query.Where("kills.kill_time <= ?", endDate)
+// groupStats groups a stats query by the given column and, when bySpace is set, by space class.
+// selectSQL must not select the space column itself.
+func groupStats(query *gorm.DB, selectSQL, groupColumn string, bySpace bool) *gorm.DB {
+	if !bySpace {
+		return query.Select(selectSQL).Group(groupColumn)
 	}
+	return query.
+		Joins("LEFT JOIN systems ON systems.system_id = kills.solar_system_id").
+		Select(selectSQL + ", " + spaceColumn + " AS space").
+		Group(groupColumn + ", " + spaceColumn)
+}
 
-	// Count total matching kills
-	err := query.Count(&totalCount).Error
-	if err != nil {
-		return nil, 0, err
+// characterStatsQuery reads from daily_character_stats when the filter fits it. The rollup is
+// aliased as kills so the grouping applies unchanged.
+func characterStatsQuery(filter KillFilter, bySpace bool) *gorm.DB {
+	if filter.fitsRollup(true, true, false) {
+		query := DB.Table("daily_character_stats AS kills").Scopes(filter.rollupScope())
+		return groupStats(query, "kills.character_id, SUM(kills.kill_count) as kill_count, SUM(kills.total_isk) as total_isk", "kills.character_id", bySpace)
 	}
 
-	// Fetch paginated results
-	err = query.
-		Offset((page - 1) * pageSize).
-		Limit(pageSize).
-		Find(&kills).Error
-
-	if err != nil {
-		return nil, 0, err
+	query := DB.Table("kills").Scopes(filter.Scope()).Joins(killEntitiesJoin, models.EntityTypeCharacter)
+	if len(filter.CharacterIDs) > 0 {
+		query = query.Where("link.entity_id IN ?", filter.CharacterIDs)
 	}
-
-	return kills, totalCount, nil
+	return groupStats(query, "link.entity_id AS character_id, COUNT(*) as kill_count, SUM(kills.total_value) as total_isk", "link.entity_id", bySpace)
 }
 
-func GetTotalKillsByRegion(regionID int, startDate, endDate string) (int64, error) {
-	var count int64
-	query := DB.Model(&models.Kill{}).Where("solar_system_id IN (SELECT system_id FROM systems WHERE region_id = ?)", regionID)
-
-	if startDate != "" {
-		startTime, _ := time.Parse("2006-01-02", startDate)
-		query = query.Where("kill_time >= ?", startTime)
-	}
+// GetCharacterStats rolls kills up per character, and per space class too when bySpace is set.
+func GetCharacterStats(filter KillFilter, bySpace bool) ([]CharacterStats, error) {
+	var stats []CharacterStats
+	err := characterStatsQuery(filter, bySpace).Find(&stats).Error
+	return stats, err
+}
 
-	if endDate != "" {
-		endTime, _ := time.Parse("2006-01-02", endDate)
-		query = query.Where("kill_time <= ?", endTime)
+// StreamCharacterStats calls fn for each character's stats row as it is read from the database.
+func StreamCharacterStats(filter KillFilter, bySpace bool, fn func(CharacterStats) error) error {
+	rows, err := characterStatsQuery(filter, bySpace).Rows()
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	result := query.Count(&count)
-	if result.Error != nil {
-		return 0, result.Error
+	for rows.Next() {
+		var stats CharacterStats
+		if err := DB.ScanRows(rows, &stats); err != nil {
+			return err
+		}
+		if err := fn(stats); err != nil {
+			return err
+		}
 	}
-	return count, nil
+	return rows.Err()
 }
 
-func GetCharacterKillmails(characterID int64, startTime, endTime time.Time, systemID, regionID int64) ([]models.Kill, error) {
-	query := DB.Where("character_id = ? AND kill_time BETWEEN ? AND ?", characterID, startTime, endTime)
-
-	if systemID != 0 {
-		query = query.Where("solar_system_id = ?", systemID)
-	}
-
-	if regionID != 0 {
-		query = query.Joins("JOIN systems ON kills.solar_system_id = systems.system_id").
-			Where("systems.region_id = ?", regionID)
+// GetRegionIDForSystem returns the region of a system, or 0 when the system is unknown.
+func GetRegionIDForSystem(systemID int) (int, error) {
+	location, err := GetSystemLocation(systemID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
 	}
-
-	var kills []models.Kill
-	err := query.Find(&kills).Error
-	return kills, err
+	return location.RegionID, err
 }
 
-type CharacterStats struct {
-	CharacterID int64   `json:"character_id"`
-	KillCount   int     `json:"kill_count"`
-	TotalISK    float64 `json:"total_isk"`
+// GetAdjacentSystemIDs returns the systems one stargate jump away.
+func GetAdjacentSystemIDs(systemID int) ([]int, error) {
+	var ids []int
+	err := DB.Model(&models.Stargate{}).
+		Where("system_id = ?", systemID).
+		Distinct().
+		Pluck("destination_system_id", &ids).Error
+	return ids, err
 }
 
-func GetCharacterStats(startTime, endTime time.Time, systemID int64, regionIDs ...int64) ([]CharacterStats, error) {
-	query := DB.Table("kills").
-		Select("character_id, COUNT(*) as kill_count, SUM(total_value) as total_isk").
-		Where("kill_time BETWEEN ? AND ?", startTime, endTime).
-		Group("character_id")
-
-	if systemID != 0 {
-		query = query.Where("solar_system_id = ?", systemID)
+// ClassifySystems stores the space class of every system whose class is missing or out of date.
+func ClassifySystems() error {
+	var rows []struct {
+		SystemID       int
+		SecurityStatus float64
+		SpaceClass     string
+		RegionID       int
+	}
+	err := DB.Table("systems").
+		Select("systems.system_id, systems.security_status, systems.space_class, COALESCE(constellations.region_id, 0) AS region_id").
+		Joins("LEFT JOIN constellations ON constellations.constellation_id = systems.constellation_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
 	}
 
-	if len(regionIDs) > 0 {
-		query = query.Joins("JOIN systems ON kills.solar_system_id = systems.system_id").
-			Where("systems.region_id IN ?", regionIDs)
+	updated := 0
+	for _, row := range rows {
+		class := models.ClassifySpace(row.RegionID, row.SecurityStatus)
+		if class == row.SpaceClass {
+			continue
+		}
+		err := DB.Model(&models.System{}).Where("system_id = ?", row.SystemID).Update("space_class", class).Error
+		if err != nil {
+			return err
+		}
+		updated++
 	}
 
-	var stats []CharacterStats
-	err := query.Find(&stats).Error
-	return stats, err
+	if updated > 0 {
+		log.Printf("Classified %d systems", updated)
+	}
+	return nil
 }
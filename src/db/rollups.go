@@ -0,0 +1,288 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// rollup is a daily rollup table and the kill columns it is keyed by besides the day. join,
+// when set, joins the table the sources come from to kills.
+type rollup struct {
+	table   string
+	columns []string
+	sources []string
+	join    string
+}
+
+var rollups = []rollup{
+	{table: "daily_character_stats", columns: []string{"character_id", "solar_system_id"}, sources: []string{"link.entity_id", "kills.solar_system_id"},
+		join: "JOIN kill_entities AS link ON link.killmail_id = kills.killmail_id AND link.entity_type = 'character'"},
+	{table: "daily_system_stats", columns: []string{"solar_system_id"}, sources: []string{"kills.solar_system_id"}},
+	{table: "daily_ship_type_stats", columns: []string{"ship_type_id"}, sources: []string{"kills.victim_ship_type_id"}},
+}
+
+// killDay is the UTC day of a kill, the bucket every rollup is keyed by. SQLite stores times
+// as text with their offset, which date() converts to UTC.
+func killDay() string {
+	if !IsPostgres() {
+		return "date(kills.kill_time)"
+	}
+	return "(kills.kill_time AT TIME ZONE 'UTC')::date"
+}
+
+// upsertSQL recomputes the rollup rows of the kills matching where from the kills table.
+func (r rollup) upsertSQL(where string) string {
+	return r.aggregateSQL(where, "", "EXCLUDED.kill_count", "EXCLUDED.total_isk")
+}
+
+// addSQL adds the kills matching where to the rollup rows, or subtracts them when sign is -1.
+func (r rollup) addSQL(where string, sign int) string {
+	return r.aggregateSQL(where, fmt.Sprintf("%d * ", sign),
+		r.table+".kill_count + EXCLUDED.kill_count", r.table+".total_isk + EXCLUDED.total_isk")
+}
+
+func (r rollup) aggregateSQL(where, factor, killCount, totalISK string) string {
+	groups := make([]string, len(r.columns))
+	for i := range r.columns {
+		groups[i] = fmt.Sprint(i + 2)
+	}
+	columns := strings.Join(r.columns, ", ")
+
+	return fmt.Sprintf(`
+        INSERT INTO %s (day, %s, kill_count, total_isk)
+        SELECT %s, %s, %sCOUNT(*), %sCOALESCE(SUM(kills.total_value), 0)
+        FROM kills %s
+        WHERE %s
+        GROUP BY 1, %s
+        ON CONFLICT (day, %s) DO UPDATE
+        SET kill_count = %s,
+            total_isk = %s`,
+		r.table, columns, killDay(), strings.Join(r.sources, ", "), factor, factor, r.join, where, strings.Join(groups, ", "), columns,
+		killCount, totalISK)
+}
+
+// pruneSQL deletes the emptied rollup rows on the days of the kills matching where.
+func (r rollup) pruneSQL(where string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE kill_count <= 0 AND day IN (SELECT %s FROM kills WHERE %s)", r.table, killDay(), where)
+}
+
+// rollupStatements returns the statements that keep the rollups in step with a batch of kills,
+// selected by where, within the transaction that merges them. The statements of before run
+// ahead of the merge and take the stored kills out of the rollups; those of after add the
+// merged kills back and delete the rows left empty, as when a kill moved to another system. A
+// reingested kill that did not change is taken out and added back, so it counts once.
+func rollupStatements(where string) (before, after []string) {
+	for _, r := range rollups {
+		before = append(before, r.addSQL(where, -1))
+		after = append(after, r.addSQL(where, 1), r.pruneSQL(where))
+	}
+	return before, after
+}
+
+// RebuildRollups recomputes every rollup from the kills table, one month at a time. Bounds
+// default to the first and last stored kill, and days before the first stored kill are never
+// rebuilt, so the rollups of archived partitions survive.
+func RebuildRollups(from, to time.Time) error {
+	first, err := killTimeBound("ASC")
+	if err != nil || first == nil {
+		return err
+	}
+	if from.IsZero() || from.Before(*first) {
+		from = *first
+	}
+	if to.IsZero() {
+		last, err := killTimeBound("DESC")
+		if err != nil {
+			return err
+		}
+		to = *last
+	}
+	from = startOfDay(from)
+	end := startOfDay(to).AddDate(0, 0, 1)
+
+	for start := from; start.Before(end); {
+		next := time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		if next.After(end) {
+			next = end
+		}
+
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			for _, r := range rollups {
+				if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE day >= ? AND day < ?", r.table), start.Format("2006-01-02"), next.Format("2006-01-02")).Error; err != nil {
+					return fmt.Errorf("error clearing %s: %v", r.table, err)
+				}
+				if err := tx.Exec(r.upsertSQL("kills.kill_time >= ? AND kills.kill_time < ?"), start, next).Error; err != nil {
+					return fmt.Errorf("error rebuilding %s: %v", r.table, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		start = next
+	}
+	return nil
+}
+
+// killTimeBound returns the first or last stored kill time, or nil when no kills are stored.
+// The bound is read from the column itself rather than MIN or MAX, whose results SQLite
+// returns as text.
+func killTimeBound(direction string) (*time.Time, error) {
+	var kill struct {
+		KillTime time.Time
+	}
+	result := DB.Table("kills").Select("kill_time").Order("kill_time " + direction).Limit(1).Scan(&kill)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &kill.KillTime, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// isDayStart reports whether t is a UTC midnight.
+func isDayStart(t time.Time) bool {
+	return t.Equal(startOfDay(t))
+}
+
+// isDayEnd reports whether t is the last instant of a UTC day, as a date-only endDate is
+// parsed, or the last second of it.
+func isDayEnd(t time.Time) bool {
+	return isDayStart(t.Add(time.Nanosecond)) || (t.Nanosecond() == 0 && isDayStart(t.Add(time.Second)))
+}
+
+// fitsRollup reports whether the filter can be answered from a daily rollup: its time range
+// covers whole UTC days and it restricts nothing beyond the dimensions the rollup keeps.
+func (f KillFilter) fitsRollup(characters, locations, shipTypes bool) bool {
+	if len(f.CorporationIDs) > 0 || len(f.AllianceIDs) > 0 || len(f.SovAllianceIDs) > 0 || len(f.FWOccupierFactionIDs) > 0 {
+		return false
+	}
+	if f.MinValue != nil || f.MaxValue != nil || f.Solo != nil || f.NPC != nil || f.Awox != nil {
+		return false
+	}
+	if !characters && len(f.CharacterIDs) > 0 {
+		return false
+	}
+	if !locations && (len(f.SystemIDs) > 0 || len(f.ConstellationIDs) > 0 || len(f.RegionIDs) > 0 || len(f.SpaceClasses) > 0) {
+		return false
+	}
+	if !shipTypes && len(f.ShipTypeIDs) > 0 {
+		return false
+	}
+	if !f.StartTime.IsZero() && !isDayStart(f.StartTime) {
+		return false
+	}
+	if !f.EndTime.IsZero() && !isDayEnd(f.EndTime) {
+		return false
+	}
+	return true
+}
+
+// rollupScope applies the filter to a rollup table aliased as kills. The filter must fit the
+// rollup.
+func (f KillFilter) rollupScope() func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if len(f.CharacterIDs) > 0 {
+			query = query.Where("kills.character_id IN ?", f.CharacterIDs)
+		}
+		query = f.locationScope(query)
+		if len(f.ShipTypeIDs) > 0 {
+			query = query.Where("kills.ship_type_id IN ?", f.ShipTypeIDs)
+		}
+		if !f.StartTime.IsZero() {
+			query = query.Where("kills.day >= ?", f.StartTime.UTC().Format("2006-01-02"))
+		}
+		if !f.EndTime.IsZero() {
+			query = query.Where("kills.day <= ?", f.EndTime.UTC().Format("2006-01-02"))
+		}
+		return query
+	}
+}
+
+// DailyStats is the number and value of kills on one UTC day.
+type DailyStats struct {
+	Day       time.Time `json:"day"`
+	KillCount int       `json:"kill_count"`
+	TotalISK  float64   `json:"total_isk"`
+}
+
+// GetKillTimeseries returns the kills matching the filter per UTC day, reading from a rollup
+// whenever the filter fits one.
+func GetKillTimeseries(filter KillFilter) ([]DailyStats, error) {
+	var query *gorm.DB
+	switch {
+	case filter.fitsRollup(false, true, false):
+		query = DB.Table("daily_system_stats AS kills")
+	case filter.fitsRollup(true, true, false):
+		query = DB.Table("daily_character_stats AS kills")
+	case filter.fitsRollup(false, false, true):
+		query = DB.Table("daily_ship_type_stats AS kills")
+	}
+
+	if query != nil {
+		query = query.Scopes(filter.rollupScope()).
+			Select("kills.day, SUM(kills.kill_count) AS kill_count, SUM(kills.total_isk) AS total_isk").
+			Group("kills.day")
+	} else {
+		query = DB.Table("kills").Scopes(filter.Scope()).
+			Select(killDay() + " AS day, COUNT(*) AS kill_count, COALESCE(SUM(kills.total_value), 0) AS total_isk").
+			Group("day")
+	}
+
+	var rows []struct {
+		Day       day
+		KillCount int
+		TotalISK  float64 `gorm:"column:total_isk"`
+	}
+	if err := query.Order("day").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make([]DailyStats, len(rows))
+	for i, row := range rows {
+		stats[i] = DailyStats{Day: time.Time(row.Day), KillCount: row.KillCount, TotalISK: row.TotalISK}
+	}
+	return stats, nil
+}
+
+// day scans a UTC day, which SQLite returns as text when it is computed from kill_time.
+type day time.Time
+
+func (d *day) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		*d = day(v)
+		return nil
+	case string:
+		t, err := time.Parse("2006-01-02", v)
+		*d = day(t)
+		return err
+	case []byte:
+		return d.Scan(string(v))
+	}
+	return fmt.Errorf("cannot scan %T into a day", value)
+}
+
+// EnsureRollups rebuilds the rollups when they are empty but kills are stored, as after
+// upgrading a database that predates them.
+func EnsureRollups() error {
+	var rolledUp, stored bool
+	if err := DB.Raw("SELECT EXISTS (SELECT 1 FROM daily_system_stats)").Scan(&rolledUp).Error; err != nil {
+		return err
+	}
+	if err := DB.Raw("SELECT EXISTS (SELECT 1 FROM kills)").Scan(&stored).Error; err != nil {
+		return err
+	}
+	if rolledUp || !stored {
+		return nil
+	}
+	return RebuildRollups(time.Time{}, time.Time{})
+}
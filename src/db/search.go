@@ -0,0 +1,121 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// Search result types.
+const (
+	SearchTypeSystem        = "system"
+	SearchTypeConstellation = "constellation"
+	SearchTypeRegion        = "region"
+	SearchTypeItem          = "item"
+	SearchTypeCharacter     = models.EntityTypeCharacter
+	SearchTypeCorporation   = models.EntityTypeCorporation
+	SearchTypeAlliance      = models.EntityTypeAlliance
+)
+
+// SearchTypes lists every searchable type in the default order.
+var SearchTypes = []string{
+	SearchTypeSystem,
+	SearchTypeConstellation,
+	SearchTypeRegion,
+	SearchTypeItem,
+	SearchTypeCharacter,
+	SearchTypeCorporation,
+	SearchTypeAlliance,
+}
+
+// searchSources maps a search type to the table, ID column and extra condition it searches.
+var searchSources = map[string]struct {
+	table, idColumn, where string
+}{
+	SearchTypeSystem:        {"systems", "system_id", ""},
+	SearchTypeConstellation: {"constellations", "constellation_id", ""},
+	SearchTypeRegion:        {"regions", "region_id", ""},
+	SearchTypeItem:          {"esi_items", "type_id", "published"},
+	SearchTypeCharacter:     {"entity_names", "id", "category = 'character'"},
+	SearchTypeCorporation:   {"entity_names", "id", "category = 'corporation'"},
+	SearchTypeAlliance:      {"entity_names", "id", "category = 'alliance'"},
+}
+
+// searchIndexes are the trigram indexes backing Search.
+var searchIndexes = map[string]string{
+	"idx_systems_name_trgm":        "systems",
+	"idx_constellations_name_trgm": "constellations",
+	"idx_regions_name_trgm":        "regions",
+	"idx_esi_items_name_trgm":      "esi_items",
+	"idx_entity_names_name_trgm":   "entity_names",
+}
+
+// InitSearchIndexes enables pg_trgm and creates the trigram indexes used by Search.
+// Creating the extension requires sufficient privileges on the database.
+func InitSearchIndexes() error {
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		return err
+	}
+	for name, table := range searchIndexes {
+		err := DB.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING gin (name gin_trgm_ops)", name, table)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type SearchResult struct {
+	Type  string  `json:"type"`
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// Search finds names matching q by prefix or trigram similarity. Exact matches rank first,
+// then prefix matches, then the rest by similarity, shorter names breaking ties.
+func Search(q string, types []string, limit int) ([]SearchResult, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	prefix := escapeLike(q) + "%"
+
+	var parts []string
+	var args []interface{}
+	for _, t := range types {
+		source, ok := searchSources[t]
+		if !ok {
+			return nil, fmt.Errorf("unknown search type %q", t)
+		}
+
+		where := "(name ILIKE ? OR name % ?)"
+		if source.where != "" {
+			where += " AND " + source.where
+		}
+		parts = append(parts, fmt.Sprintf(`SELECT ? AS type, %s::bigint AS id, name,
+                similarity(name, ?) AS score,
+                lower(name) = lower(?) AS exact,
+                name ILIKE ? AS prefix
+            FROM %s WHERE %s`, source.idColumn, source.table, where))
+		args = append(args, t, q, q, prefix, prefix, q)
+	}
+
+	results := []SearchResult{}
+	if len(parts) == 0 {
+		return results, nil
+	}
+
+	query := "SELECT type, id, name, score FROM (" + strings.Join(parts, " UNION ALL ") + `) matches
+        ORDER BY exact DESC, prefix DESC, score DESC, length(name), name
+        LIMIT ?`
+	args = append(args, limit)
+
+	err := DB.Raw(query, args...).Scan(&results).Error
+	return results, err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
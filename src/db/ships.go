@@ -0,0 +1,215 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// WeaponUsage counts kills on which a weapon was used from a given ship.
+type WeaponUsage struct {
+	WeaponTypeID int    `json:"weapon_type_id"`
+	WeaponName   string `json:"weapon_name"`
+	Kills        int    `json:"kills" gorm:"column:kills"`
+}
+
+// ShipUsage summarises the kills a character took part in flying one ship type.
+type ShipUsage struct {
+	ShipTypeID    int           `json:"ship_type_id"`
+	ShipName      string        `json:"ship_name"`
+	GroupID       int           `json:"group_id"`
+	GroupName     string        `json:"group_name"`
+	Kills         int           `json:"kills" gorm:"column:kills"`
+	DamageShare   float64       `json:"damage_share"`
+	FinalBlows    int           `json:"final_blows"`
+	FinalBlowRate float64       `json:"final_blow_rate"`
+	FirstUsed     time.Time     `json:"first_used"`
+	LastUsed      time.Time     `json:"last_used"`
+	Weapons       []WeaponUsage `json:"weapons" gorm:"-"`
+}
+
+// attackerScope restricts a kills query joined with its attackers as "attacker" to the rows
+// of the given character, corporation or alliance.
+func attackerScope(entityType string, entityID int64) (string, []interface{}, error) {
+	key := associateKeys[entityType]
+	if key == "" {
+		return "", nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+	return "kills.attackers @> ?::jsonb AND attacker->>? = ?",
+		[]interface{}{fmt.Sprintf(`[{%q: %d}]`, key, entityID), key, fmt.Sprint(entityID)},
+		nil
+}
+
+// GetCharacterShipUsage returns the ship types a character attacked in, most used first.
+// Damage share is the character's damage as a fraction of the victim's damage taken, averaged over kills.
+func GetCharacterShipUsage(characterID int64, filter KillFilter) ([]ShipUsage, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	where, args, err := attackerScope(models.EntityTypeCharacter, characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ships []ShipUsage
+	err = DB.Table("kills").
+		Scopes(filter.Scope()).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(kills.attackers) AS attacker").
+		Joins("LEFT JOIN esi_items ON esi_items.type_id = (attacker->>'ship_type_id')::int").
+		Joins("LEFT JOIN item_groups ON item_groups.group_id = esi_items.group_id").
+		Where(where, args...).
+		Select(`(attacker->>'ship_type_id')::int AS ship_type_id,
+                COALESCE(MAX(esi_items.name), '') AS ship_name,
+                COALESCE(MAX(esi_items.group_id), 0) AS group_id,
+                COALESCE(MAX(item_groups.name), '') AS group_name,
+                COUNT(DISTINCT kills.killmail_id) AS kills,
+                COALESCE(AVG((attacker->>'damage_done')::float8 / NULLIF(kills.victim_damage_taken, 0)), 0) AS damage_share,
+                SUM(CASE WHEN (attacker->>'final_blow')::boolean THEN 1 ELSE 0 END) AS final_blows,
+                AVG(CASE WHEN (attacker->>'final_blow')::boolean THEN 1.0 ELSE 0.0 END) AS final_blow_rate,
+                MIN(kills.kill_time) AS first_used,
+                MAX(kills.kill_time) AS last_used`).
+		Group("1").
+		Order("kills DESC").
+		Scan(&ships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var weapons []struct {
+		ShipTypeID int
+		WeaponUsage
+	}
+	err = DB.Table("kills").
+		Scopes(filter.Scope()).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(kills.attackers) AS attacker").
+		Joins("LEFT JOIN esi_items ON esi_items.type_id = (attacker->>'weapon_type_id')::int").
+		Where(where, args...).
+		Select(`(attacker->>'ship_type_id')::int AS ship_type_id,
+                (attacker->>'weapon_type_id')::int AS weapon_type_id,
+                COALESCE(MAX(esi_items.name), '') AS weapon_name,
+                COUNT(DISTINCT kills.killmail_id) AS kills`).
+		Group("1, 2").
+		Order("kills DESC").
+		Scan(&weapons).Error
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[int]int, len(ships))
+	for i := range ships {
+		index[ships[i].ShipTypeID] = i
+		ships[i].Weapons = []WeaponUsage{}
+	}
+	for _, weapon := range weapons {
+		if i, ok := index[weapon.ShipTypeID]; ok {
+			ships[i].Weapons = append(ships[i].Weapons, weapon.WeaponUsage)
+		}
+	}
+
+	return ships, nil
+}
+
+// DoctrineShip is one ship type flown within a ship group.
+type DoctrineShip struct {
+	ShipTypeID int       `json:"ship_type_id"`
+	ShipName   string    `json:"ship_name"`
+	Kills      int       `json:"kills"`
+	Pilots     int       `json:"pilots"`
+	LastUsed   time.Time `json:"last_used"`
+}
+
+// DoctrineGroup aggregates the ships a corporation or alliance fielded by ship group.
+type DoctrineGroup struct {
+	GroupID   int            `json:"group_id"`
+	GroupName string         `json:"group_name"`
+	Kills     int            `json:"kills"`
+	Pilots    int            `json:"pilots"`
+	LastUsed  time.Time      `json:"last_used"`
+	Ships     []DoctrineShip `json:"ships"`
+}
+
+// GetDoctrineUsage returns the ship groups members of a corporation or alliance attacked in,
+// most used first. Group totals count each kill and pilot once even across ship types.
+func GetDoctrineUsage(entityType string, entityID int64, filter KillFilter) ([]DoctrineGroup, error) {
+	if err := requirePostgres(); err != nil {
+		return nil, err
+	}
+
+	where, args, err := attackerScope(entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		GroupID    int
+		GroupName  string
+		IsTotal    bool
+		ShipTypeID *int
+		ShipName   string
+		Kills      int `gorm:"column:kills"`
+		Pilots     int
+		LastUsed   time.Time
+	}
+	err = DB.Table("kills").
+		Scopes(filter.Scope()).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements(kills.attackers) AS attacker").
+		Joins("LEFT JOIN esi_items ON esi_items.type_id = (attacker->>'ship_type_id')::int").
+		Joins("LEFT JOIN item_groups ON item_groups.group_id = esi_items.group_id").
+		Where(where, args...).
+		Select(`GROUPING((attacker->>'ship_type_id')::int) = 1 AS is_total,
+                COALESCE(esi_items.group_id, 0) AS group_id,
+                COALESCE(MAX(item_groups.name), '') AS group_name,
+                (attacker->>'ship_type_id')::int AS ship_type_id,
+                COALESCE(MAX(esi_items.name), '') AS ship_name,
+                COUNT(DISTINCT kills.killmail_id) AS kills,
+                COUNT(DISTINCT attacker->>'character_id') AS pilots,
+                MAX(kills.kill_time) AS last_used`).
+		Group("GROUPING SETS ((COALESCE(esi_items.group_id, 0)), (COALESCE(esi_items.group_id, 0), (attacker->>'ship_type_id')::int))").
+		Order("kills DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []DoctrineGroup
+	index := make(map[int]int)
+	// Group totals are told apart by GROUPING, as attackers without a ship type also leave
+	// ship_type_id NULL in their per-ship row.
+	for _, row := range rows {
+		if row.IsTotal {
+			index[row.GroupID] = len(groups)
+			groups = append(groups, DoctrineGroup{
+				GroupID:   row.GroupID,
+				GroupName: row.GroupName,
+				Kills:     row.Kills,
+				Pilots:    row.Pilots,
+				LastUsed:  row.LastUsed,
+				Ships:     []DoctrineShip{},
+			})
+		}
+	}
+	for _, row := range rows {
+		if row.IsTotal {
+			continue
+		}
+		shipTypeID := 0
+		if row.ShipTypeID != nil {
+			shipTypeID = *row.ShipTypeID
+		}
+		i := index[row.GroupID]
+		if groups[i].GroupName == "" {
+			groups[i].GroupName = row.GroupName
+		}
+		groups[i].Ships = append(groups[i].Ships, DoctrineShip{
+			ShipTypeID: shipTypeID,
+			ShipName:   row.ShipName,
+			Kills:      row.Kills,
+			Pilots:     row.Pilots,
+			LastUsed:   row.LastUsed,
+		})
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+// RecordSovereignty applies a sovereignty map snapshot taken at the given time. Systems whose
+// holder changed get their open row closed and a new one opened; systems no longer held are closed.
+func RecordSovereignty(snapshot []models.SovereigntyHistory, at time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var open []models.SovereigntyHistory
+		if err := tx.Where("valid_to IS NULL").Find(&open).Error; err != nil {
+			return err
+		}
+		current := make(map[int]models.SovereigntyHistory, len(open))
+		for _, row := range open {
+			current[row.SystemID] = row
+		}
+
+		seen := make(map[int]bool, len(snapshot))
+		for _, entry := range snapshot {
+			seen[entry.SystemID] = true
+			existing, ok := current[entry.SystemID]
+			if ok && existing.AllianceID == entry.AllianceID && existing.CorporationID == entry.CorporationID && existing.FactionID == entry.FactionID {
+				continue
+			}
+			if ok {
+				if err := tx.Model(&existing).Update("valid_to", at).Error; err != nil {
+					return err
+				}
+			}
+			entry.ID = 0
+			entry.ValidFrom = at
+			entry.ValidTo = nil
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+
+		for systemID, row := range current {
+			if !seen[systemID] {
+				if err := tx.Model(&row).Update("valid_to", at).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// RecordSovereigntyStructures applies a sovereignty structure snapshot taken at the given time.
+// A change of owning alliance opens a new row; occupancy and vulnerability are updated in place.
+func RecordSovereigntyStructures(snapshot []models.SovereigntyStructureHistory, at time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var open []models.SovereigntyStructureHistory
+		if err := tx.Where("valid_to IS NULL").Find(&open).Error; err != nil {
+			return err
+		}
+		current := make(map[int64]models.SovereigntyStructureHistory, len(open))
+		for _, row := range open {
+			current[row.StructureID] = row
+		}
+
+		seen := make(map[int64]bool, len(snapshot))
+		for _, entry := range snapshot {
+			seen[entry.StructureID] = true
+			existing, ok := current[entry.StructureID]
+			if ok && existing.AllianceID == entry.AllianceID {
+				err := tx.Model(&existing).Updates(map[string]interface{}{
+					"vulnerability_occupancy_level": entry.VulnerabilityOccupancyLevel,
+					"vulnerable_start_time":         entry.VulnerableStartTime,
+					"vulnerable_end_time":           entry.VulnerableEndTime,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if ok {
+				if err := tx.Model(&existing).Update("valid_to", at).Error; err != nil {
+					return err
+				}
+			}
+			entry.ID = 0
+			entry.ValidFrom = at
+			entry.ValidTo = nil
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+
+		for structureID, row := range current {
+			if !seen[structureID] {
+				if err := tx.Model(&row).Update("valid_to", at).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// RecordFactionWarfare applies a faction warfare snapshot taken at the given time. A change of
+// owner or occupier opens a new row; contest status and victory points are updated in place.
+func RecordFactionWarfare(snapshot []models.FactionWarfareHistory, at time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var open []models.FactionWarfareHistory
+		if err := tx.Where("valid_to IS NULL").Find(&open).Error; err != nil {
+			return err
+		}
+		current := make(map[int]models.FactionWarfareHistory, len(open))
+		for _, row := range open {
+			current[row.SystemID] = row
+		}
+
+		seen := make(map[int]bool, len(snapshot))
+		for _, entry := range snapshot {
+			seen[entry.SystemID] = true
+			existing, ok := current[entry.SystemID]
+			if ok && existing.OwnerFactionID == entry.OwnerFactionID && existing.OccupierFactionID == entry.OccupierFactionID {
+				err := tx.Model(&existing).Updates(map[string]interface{}{
+					"contested":                entry.Contested,
+					"victory_points":           entry.VictoryPoints,
+					"victory_points_threshold": entry.VictoryPointsThreshold,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if ok {
+				if err := tx.Model(&existing).Update("valid_to", at).Error; err != nil {
+					return err
+				}
+			}
+			entry.ID = 0
+			entry.ValidFrom = at
+			entry.ValidTo = nil
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+
+		for systemID, row := range current {
+			if !seen[systemID] {
+				if err := tx.Model(&row).Update("valid_to", at).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// PoliticalOverlay is the sovereignty and faction warfare state of a kill's system at the kill time.
+// Faction warfare contest status and victory points are the latest known for that occupancy.
+type PoliticalOverlay struct {
+	Sovereignty    *models.SovereigntyHistory    `json:"sovereignty,omitempty"`
+	FactionWarfare *models.FactionWarfareHistory `json:"faction_warfare,omitempty"`
+}
+
+// GetPoliticalOverlays returns the overlay of each kill that has one, keyed by killmail ID.
+func GetPoliticalOverlays(kills []models.Kill) (map[int64]PoliticalOverlay, error) {
+	overlays := make(map[int64]PoliticalOverlay)
+	if len(kills) == 0 {
+		return overlays, nil
+	}
+
+	systemIDs := make([]int, 0, len(kills))
+	for _, kill := range kills {
+		systemIDs = append(systemIDs, kill.SolarSystemID)
+	}
+
+	var sov []models.SovereigntyHistory
+	if err := DB.Where("system_id IN ?", systemIDs).Find(&sov).Error; err != nil {
+		return nil, err
+	}
+	var fw []models.FactionWarfareHistory
+	if err := DB.Where("system_id IN ?", systemIDs).Find(&fw).Error; err != nil {
+		return nil, err
+	}
+
+	for _, kill := range kills {
+		var overlay PoliticalOverlay
+		for i := range sov {
+			if sov[i].SystemID == kill.SolarSystemID && validAt(sov[i].ValidFrom, sov[i].ValidTo, kill.KillTime) {
+				overlay.Sovereignty = &sov[i]
+				break
+			}
+		}
+		for i := range fw {
+			if fw[i].SystemID == kill.SolarSystemID && validAt(fw[i].ValidFrom, fw[i].ValidTo, kill.KillTime) {
+				overlay.FactionWarfare = &fw[i]
+				break
+			}
+		}
+		if overlay.Sovereignty != nil || overlay.FactionWarfare != nil {
+			overlays[kill.KillmailID] = overlay
+		}
+	}
+	return overlays, nil
+}
+
+func validAt(from time.Time, to *time.Time, t time.Time) bool {
+	return !t.Before(from) && (to == nil || t.Before(*to))
+}
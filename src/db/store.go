@@ -0,0 +1,185 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+// Store is the storage backend behind the package functions for characters, kills and
+// universe data. Postgres is the production backend; SQLite runs the API locally without
+// any services.
+//
+// Store only covers writes and lookups whose SQL differs between the backends. Listing,
+// stats and filters go through DB directly, since gorm builds them portably; queries that
+// need Postgres features check IsPostgres and return ErrUnsupported on other backends.
+type Store interface {
+	// Name identifies the backend, as selected by DB_DRIVER.
+	Name() string
+	// Migrate prepares what AutoMigrate cannot, such as partitions and indexes.
+	Migrate() error
+
+	InsertCharacter(character *models.Character) error
+	GetCharacterByID(id int64) (*models.Character, error)
+	GetAllCharacters() ([]models.Character, error)
+
+	// UpsertKills writes kills and the rollup rows they touch, without notifying listeners.
+	// merge selects the columns of stored kills that are overwritten. It returns the killmail
+	// IDs that were not stored before.
+	UpsertKills(kills []models.Kill, merge KillMerge) (inserted []int64, err error)
+	GetKillByKillmailID(killmailID int64) (*models.Kill, error)
+	GetLastKillTimeForEntity(entityType string, entityID int64) (time.Time, error)
+
+	UpsertRegions(regions []models.Region) error
+	UpsertConstellations(constellations []models.Constellation) error
+	UpsertSystems(systems []models.System) error
+	UpsertStargates(stargates []models.Stargate) error
+	UpsertESIItems(items []models.ESIItem) error
+	UpsertItemGroups(groups []models.ItemGroup) error
+	UpsertLocalizedNames(names []models.LocalizedName) error
+	GetRegion(id int) (*models.Region, error)
+	GetConstellation(id int) (*models.Constellation, error)
+	GetSystem(id int) (*models.System, error)
+}
+
+// store is the backend opened by InitDB.
+var store Store
+
+// ErrUnsupported is returned by queries that rely on Postgres features, such as jsonb
+// operators or partitions, when another backend is in use.
+var ErrUnsupported = errors.New("not supported by this database backend")
+
+// IsPostgres reports whether the Postgres backend is in use.
+func IsPostgres() bool {
+	return store == nil || store.Name() == "postgres"
+}
+
+func requirePostgres() error {
+	if !IsPostgres() {
+		return ErrUnsupported
+	}
+	return nil
+}
+
+// gormStore implements the parts of Store that plain gorm expresses on every backend.
+type gormStore struct {
+	db *gorm.DB
+}
+
+func (s gormStore) InsertCharacter(character *models.Character) error {
+	return s.db.Create(character).Error
+}
+
+func (s gormStore) GetCharacterByID(id int64) (*models.Character, error) {
+	var character models.Character
+	err := s.db.First(&character, id).Error
+	return &character, err
+}
+
+func (s gormStore) GetAllCharacters() ([]models.Character, error) {
+	var characters []models.Character
+	err := s.db.Find(&characters).Error
+	return characters, err
+}
+
+func (s gormStore) GetKillByKillmailID(killmailID int64) (*models.Kill, error) {
+	var kill models.Kill
+	err := s.db.Where("killmail_id = ?", killmailID).First(&kill).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &kill, err
+}
+
+func (s gormStore) GetLastKillTimeForEntity(entityType string, entityID int64) (time.Time, error) {
+	var lastKill struct {
+		KillTime time.Time
+	}
+
+	result := s.db.Table("kill_entities").
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("kill_time DESC").
+		Limit(1).
+		Select("kill_time").
+		Scan(&lastKill)
+
+	if result.Error != nil {
+		return time.Time{}, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return time.Time{}, nil
+	}
+
+	return lastKill.KillTime, nil
+}
+
+func (s gormStore) UpsertRegions(regions []models.Region) error {
+	regions = dedupe(regions, func(r *models.Region) int { return r.RegionID })
+	return upsertRows(s.db, regions, []string{"region_id"}, []string{"name", "description", "constellations"})
+}
+
+func (s gormStore) UpsertConstellations(constellations []models.Constellation) error {
+	constellations = dedupe(constellations, func(c *models.Constellation) int { return c.ConstellationID })
+	return upsertRows(s.db, constellations, []string{"constellation_id"}, []string{"name", "region_id", "systems", "position"})
+}
+
+// UpsertSystems leaves space_class alone; ClassifySystems maintains it.
+func (s gormStore) UpsertSystems(systems []models.System) error {
+	systems = dedupe(systems, func(s *models.System) int { return s.SystemID })
+	return upsertRows(s.db, systems, []string{"system_id"}, []string{
+		"constellation_id", "name", "security_class", "security_status", "star_id", "planets", "stargates", "stations", "position",
+	})
+}
+
+func (s gormStore) UpsertStargates(stargates []models.Stargate) error {
+	stargates = dedupe(stargates, func(s *models.Stargate) int { return s.StargateID })
+	return upsertRows(s.db, stargates, []string{"stargate_id"}, []string{"name", "system_id", "destination_stargate_id", "destination_system_id"})
+}
+
+func (s gormStore) UpsertESIItems(items []models.ESIItem) error {
+	items = dedupe(items, func(i *models.ESIItem) int { return i.TypeID })
+	return upsertRows(s.db, items, []string{"type_id"}, []string{
+		"group_id", "name", "description", "mass", "volume", "capacity", "portion_size", "packaged_volume", "published", "radius",
+	})
+}
+
+func (s gormStore) UpsertItemGroups(groups []models.ItemGroup) error {
+	groups = dedupe(groups, func(g *models.ItemGroup) int { return g.GroupID })
+	return upsertRows(s.db, groups, []string{"group_id"}, []string{"name", "category_id", "published"})
+}
+
+func (s gormStore) UpsertLocalizedNames(names []models.LocalizedName) error {
+	type key struct {
+		kind, language string
+		id             int
+	}
+	names = dedupe(names, func(n *models.LocalizedName) key { return key{n.Kind, n.Language, n.ID} })
+	return upsertRows(s.db, names, []string{"kind", "id", "language"}, []string{"name", "description"})
+}
+
+func (s gormStore) GetRegion(id int) (*models.Region, error) {
+	var region models.Region
+	if err := s.db.First(&region, id).Error; err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
+
+func (s gormStore) GetConstellation(id int) (*models.Constellation, error) {
+	var constellation models.Constellation
+	if err := s.db.First(&constellation, id).Error; err != nil {
+		return nil, err
+	}
+	return &constellation, nil
+}
+
+func (s gormStore) GetSystem(id int) (*models.System, error) {
+	var system models.System
+	if err := s.db.First(&system, id).Error; err != nil {
+		return nil, err
+	}
+	return &system, nil
+}
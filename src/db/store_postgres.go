@@ -0,0 +1,58 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// postgresStore keeps kills in monthly partitions and bulk loads them with COPY.
+type postgresStore struct {
+	gormStore
+}
+
+func openPostgres() (*gorm.DB, Store, error) {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"))
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, postgresStore{gormStore{db}}, nil
+}
+
+func (postgresStore) Name() string {
+	return "postgres"
+}
+
+func (postgresStore) Migrate() error {
+	if err := PartitionKills(); err != nil {
+		return fmt.Errorf("error partitioning kills: %v", err)
+	}
+
+	if err := InitSearchIndexes(); err != nil {
+		log.Println("Failed to create search indexes:", err)
+	}
+
+	if err := BackfillKillAffiliations(); err != nil {
+		log.Println("Failed to backfill kill affiliations:", err)
+	}
+
+	// Links are backfilled from the affiliations filled in above.
+	if err := BackfillKillEntities(); err != nil {
+		return fmt.Errorf("error linking kills to entities: %v", err)
+	}
+	return nil
+}
+
+func (postgresStore) UpsertKills(kills []models.Kill, merge KillMerge) ([]int64, error) {
+	return copyKills(kills, merge)
+}
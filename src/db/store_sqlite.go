@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/glebarez/sqlite"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+const defaultSQLitePath = "eve-ran.db"
+
+// sqliteStore keeps everything in a single SQLite file, for running the API locally. The
+// driver is pure Go, so it works in CGO-free builds. Kills are not partitioned, and the
+// analytics that need jsonb operators return ErrUnsupported.
+type sqliteStore struct {
+	gormStore
+}
+
+// openSQLite opens SQLITE_PATH, or eve-ran.db in the working directory. WAL and a busy
+// timeout let the ingest jobs write while the API reads.
+func openSQLite() (*gorm.DB, Store, error) {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = defaultSQLitePath
+	}
+
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(10000)"), &gorm.Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, sqliteStore{gormStore{db}}, nil
+}
+
+func (sqliteStore) Name() string {
+	return "sqlite"
+}
+
+func (sqliteStore) Migrate() error {
+	return BackfillKillEntities()
+}
+
+// UpsertKills writes kills with multi-row upserts, keyed by killmail_id alone as kills are not
+// partitioned. The kills already stored are read in the same transaction, as SQLite cannot
+// tell an insert from an update in RETURNING.
+func (s sqliteStore) UpsertKills(kills []models.Kill, merge KillMerge) ([]int64, error) {
+	kills = dedupe(kills, func(k *models.Kill) int64 { return k.KillmailID })
+	ids := make([]int64, len(kills))
+	for i := range kills {
+		ids[i] = kills[i].KillmailID
+	}
+
+	var inserted []int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing []int64
+		if err := tx.Model(&models.Kill{}).Where("killmail_id IN ?", ids).Pluck("killmail_id", &existing).Error; err != nil {
+			return err
+		}
+		stored := make(map[int64]bool, len(existing))
+		for _, id := range existing {
+			stored[id] = true
+		}
+		for _, id := range ids {
+			if !stored[id] {
+				inserted = append(inserted, id)
+			}
+		}
+
+		before, after := rollupStatements("kills.killmail_id IN @ids")
+		for _, statement := range before {
+			if err := tx.Exec(statement, sql.Named("ids", ids)).Error; err != nil {
+				return fmt.Errorf("error updating rollups: %v", err)
+			}
+		}
+		if err := upsertRowsSet(tx, kills, []string{"killmail_id"}, killUpdates(merge).set()); err != nil {
+			return err
+		}
+		if merge != MergeZKillboard {
+			if err := linkKills(tx, kills); err != nil {
+				return fmt.Errorf("error linking kills: %v", err)
+			}
+		}
+		for _, statement := range after {
+			if err := tx.Exec(statement, sql.Named("ids", ids)).Error; err != nil {
+				return fmt.Errorf("error updating rollups: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
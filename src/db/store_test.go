@@ -0,0 +1,135 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+func TestStoreCharacters(t *testing.T) {
+	for _, id := range []int64{5001, 5002} {
+		if err := InsertCharacter(&models.Character{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := InsertCharacter(&models.Character{ID: 5001}); err == nil {
+		t.Error("inserting a tracked character again succeeded")
+	}
+
+	character, err := GetCharacterByID(5002)
+	if err != nil || character.ID != 5002 {
+		t.Errorf("GetCharacterByID(5002) = %+v, %v", character, err)
+	}
+	if _, err := GetCharacterByID(5999); err == nil {
+		t.Error("GetCharacterByID found an untracked character")
+	}
+
+	characters, err := GetAllCharacters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, character := range characters {
+		if character.ID == 5001 || character.ID == 5002 {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("GetAllCharacters returned %d of the 2 inserted characters", found)
+	}
+}
+
+func TestStoreUpsertKillsReportsInserted(t *testing.T) {
+	killTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	first := []models.Kill{{KillmailID: 6001, KillTime: killTime}, {KillmailID: 6002, KillTime: killTime}}
+	inserted, err := store.UpsertKills(first, MergeAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(inserted) != fmt.Sprint([]int64{6001, 6002}) {
+		t.Errorf("first upsert inserted %v, want [6001 6002]", inserted)
+	}
+
+	second := []models.Kill{{KillmailID: 6002, KillTime: killTime}, {KillmailID: 6003, KillTime: killTime}}
+	inserted, err = store.UpsertKills(second, MergeAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(inserted) != fmt.Sprint([]int64{6003}) {
+		t.Errorf("second upsert inserted %v, want [6003]", inserted)
+	}
+
+	if kill, err := GetKillByKillmailID(6999); err != nil || kill != nil {
+		t.Errorf("GetKillByKillmailID on a missing kill = %v, %v; want nil, nil", kill, err)
+	}
+}
+
+func TestStoreLastKillTimeForEntity(t *testing.T) {
+	older := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(36 * time.Hour)
+	kills := []models.Kill{
+		{KillmailID: 7001, CorporationID: 98000001, KillTime: newer},
+		{KillmailID: 7002, CorporationID: 98000001, KillTime: older},
+	}
+	if err := UpsertKills(kills); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := GetLastKillTimeForEntity(models.EntityTypeCorporation, 98000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last.Equal(newer) {
+		t.Errorf("last kill time = %v, want %v", last, newer)
+	}
+
+	last, err = GetLastKillTimeForEntity(models.EntityTypeCorporation, 98000002)
+	if err != nil || !last.IsZero() {
+		t.Errorf("last kill time without kills = %v, %v; want the zero time", last, err)
+	}
+}
+
+func TestStoreUniverse(t *testing.T) {
+	regions := []models.Region{{RegionID: 10000002, Name: "The Forge", Constellations: models.IntArray{20000020}}}
+	if err := UpsertRegions(regions); err != nil {
+		t.Fatal(err)
+	}
+	regions[0].Name = "Forge"
+	if err := UpsertRegions(regions); err != nil {
+		t.Fatal(err)
+	}
+	region, err := GetRegion(10000002)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region.Name != "Forge" || fmt.Sprint(region.Constellations) != "[20000020]" {
+		t.Errorf("GetRegion = %+v", region)
+	}
+
+	systems := []models.System{{SystemID: 30000142, ConstellationID: 20000020, Name: "Jita", SecurityStatus: 0.9, Stargates: models.IntArray{50001248}}}
+	if err := UpsertSystems(systems); err != nil {
+		t.Fatal(err)
+	}
+	system, err := GetSystem(30000142)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if system.Name != "Jita" || system.SecurityStatus != 0.9 || fmt.Sprint(system.Stargates) != "[50001248]" {
+		t.Errorf("GetSystem = %+v", system)
+	}
+}
+
+func TestPostgresQueriesUnsupported(t *testing.T) {
+	if _, err := Search("jita", nil, 10); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Search error = %v, want ErrUnsupported", err)
+	}
+	if _, _, err := GetBattles(KillFilter{}, 0, 1, 10); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetBattles error = %v, want ErrUnsupported", err)
+	}
+	if _, err := ResetKillValuations(KillFilter{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ResetKillValuations error = %v, want ErrUnsupported", err)
+	}
+}
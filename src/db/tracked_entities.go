@@ -0,0 +1,90 @@
+package db
+
+import (
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+func InsertTrackedEntity(entity *models.TrackedEntity) error {
+	return DB.Create(entity).Error
+}
+
+func DeleteTrackedEntity(entityType string, entityID int64) error {
+	return DB.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Delete(&models.TrackedEntity{}).Error
+}
+
+func GetTrackedEntities(entityType string) ([]models.TrackedEntity, error) {
+	var entities []models.TrackedEntity
+	query := DB.Model(&models.TrackedEntity{})
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	err := query.Order("entity_id").Find(&entities).Error
+	return entities, err
+}
+
+// entityColumn maps an entity type to the kills column that attributes a kill to it. What a
+// kill counts for is read from kill_entities; the column only holds its latest attribution.
+func entityColumn(entityType string) string {
+	switch entityType {
+	case models.EntityTypeCorporation:
+		return "corporation_id"
+	case models.EntityTypeAlliance:
+		return "alliance_id"
+	default:
+		return "character_id"
+	}
+}
+
+func GetLastKillTimeForEntity(entityType string, entityID int64) (time.Time, error) {
+	return store.GetLastKillTimeForEntity(entityType, entityID)
+}
+
+// EntityStats is a rollup of kills for a corporation or alliance.
+type EntityStats struct {
+	EntityID    int64   `json:"entity_id"`
+	Space       string  `json:"space,omitempty"`
+	KillCount   int     `json:"kill_count"`
+	TotalISK    float64 `json:"total_isk"`
+	MemberCount int     `json:"member_count"`
+}
+
+// GetEntityStats rolls kills up per corporation or alliance they are linked to, so a kill
+// involving several tracked entities counts for each. Members are the entity's characters seen
+// on its kills.
+func GetEntityStats(entityType string, filter KillFilter, bySpace bool) ([]EntityStats, error) {
+	query := DB.Table("kills").
+		Scopes(filter.Scope()).
+		Joins(killEntitiesJoin, entityType)
+	switch entityType {
+	case models.EntityTypeCorporation:
+		if len(filter.CorporationIDs) > 0 {
+			query = query.Where("link.entity_id IN ?", filter.CorporationIDs)
+		}
+	case models.EntityTypeAlliance:
+		if len(filter.AllianceIDs) > 0 {
+			query = query.Where("link.entity_id IN ?", filter.AllianceIDs)
+		}
+	}
+	query = groupStats(query, "link.entity_id as entity_id, COUNT(*) as kill_count, SUM(kills.total_value) as total_isk, COUNT(DISTINCT NULLIF(link.character_id, 0)) as member_count", "link.entity_id", bySpace)
+
+	var stats []EntityStats
+	err := query.Find(&stats).Error
+	return stats, err
+}
+
+// BackfillKillAffiliations fills in the corporation and alliance of the tracked character
+// for kills stored before kills were attributed to corporations and alliances.
+func BackfillKillAffiliations() error {
+	return DB.Exec(`
+        UPDATE kills
+        SET corporation_id = COALESCE((a.value->>'corporation_id')::bigint, 0),
+            alliance_id = COALESCE((a.value->>'alliance_id')::bigint, 0)
+        FROM kills k
+        CROSS JOIN LATERAL jsonb_array_elements(k.attackers) a
+        WHERE kills.killmail_id = k.killmail_id
+            AND kills.corporation_id = 0
+            AND (a.value->>'character_id')::bigint = kills.character_id
+    `).Error
+}
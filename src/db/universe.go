@@ -0,0 +1,167 @@
+package db
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"gorm.io/gorm"
+)
+
+// UniverseQuery pages and projects a universe listing. A zero PageSize returns every row.
+// Fields are column names; an empty list selects all columns.
+type UniverseQuery struct {
+	Page     int
+	PageSize int
+	Fields   []string
+}
+
+type SystemFilter struct {
+	RegionIDs        []int
+	ConstellationIDs []int
+	SpaceClasses     []string
+	MinSecurity      *float64
+	MaxSecurity      *float64
+}
+
+type ConstellationFilter struct {
+	RegionIDs []int
+}
+
+type ItemFilter struct {
+	GroupIDs  []int
+	Published *bool
+}
+
+// ParseSystemFilter reads regionID, constellationID, space, minSecurity and maxSecurity.
+func ParseSystemFilter(values url.Values) (SystemFilter, error) {
+	var filter SystemFilter
+	var err error
+
+	if filter.RegionIDs, err = parseIntList(values, "regionID"); err != nil {
+		return filter, err
+	}
+	if filter.ConstellationIDs, err = parseIntList(values, "constellationID"); err != nil {
+		return filter, err
+	}
+	if filter.SpaceClasses, err = parseSpaceList(values, "space"); err != nil {
+		return filter, err
+	}
+	if filter.MinSecurity, err = parseSecurityParam(values, "minSecurity"); err != nil {
+		return filter, err
+	}
+	if filter.MaxSecurity, err = parseSecurityParam(values, "maxSecurity"); err != nil {
+		return filter, err
+	}
+	if filter.MinSecurity != nil && filter.MaxSecurity != nil && *filter.MinSecurity > *filter.MaxSecurity {
+		return filter, &FilterError{Param: "maxSecurity", Message: "must not be below minSecurity"}
+	}
+	return filter, nil
+}
+
+// ParseConstellationFilter reads regionID.
+func ParseConstellationFilter(values url.Values) (ConstellationFilter, error) {
+	var filter ConstellationFilter
+	var err error
+	filter.RegionIDs, err = parseIntList(values, "regionID")
+	return filter, err
+}
+
+// ParseItemFilter reads groupID and published.
+func ParseItemFilter(values url.Values) (ItemFilter, error) {
+	var filter ItemFilter
+	var err error
+
+	if filter.GroupIDs, err = parseIntList(values, "groupID"); err != nil {
+		return filter, err
+	}
+	if filter.Published, err = parseBoolParam(values, "published"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseSecurityParam(values url.Values, name string) (*float64, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < -1 || f > 1 {
+		return nil, &FilterError{Param: name, Message: "expected a number between -1 and 1"}
+	}
+	return &f, nil
+}
+
+// listUniverse runs a filtered universe query, returning the requested page and the total row count.
+// The key column is ordered by and always selected, so projected rows can still be localized.
+func listUniverse[T any](query *gorm.DB, key string, q UniverseQuery) ([]T, int64, error) {
+	var total int64
+	if q.PageSize > 0 {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, 0, err
+		}
+		query = query.Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize)
+	}
+	if len(q.Fields) > 0 {
+		columns := q.Fields
+		if !slices.Contains(columns, key) {
+			columns = append([]string{key}, columns...)
+		}
+		query = query.Select(columns)
+	}
+
+	rows := []T{}
+	if err := query.Order(key).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	if q.PageSize == 0 {
+		total = int64(len(rows))
+	}
+	return rows, total, nil
+}
+
+func ListSystems(filter SystemFilter, q UniverseQuery) ([]models.System, int64, error) {
+	query := DB.Model(&models.System{})
+	if len(filter.RegionIDs) > 0 {
+		query = query.Where("constellation_id IN (SELECT constellation_id FROM constellations WHERE region_id IN ?)", filter.RegionIDs)
+	}
+	if len(filter.ConstellationIDs) > 0 {
+		query = query.Where("constellation_id IN ?", filter.ConstellationIDs)
+	}
+	if len(filter.SpaceClasses) > 0 {
+		query = query.Where("space_class IN ?", filter.SpaceClasses)
+	}
+	if filter.MinSecurity != nil {
+		query = query.Where("security_status >= ?", *filter.MinSecurity)
+	}
+	if filter.MaxSecurity != nil {
+		query = query.Where("security_status <= ?", *filter.MaxSecurity)
+	}
+	return listUniverse[models.System](query, "system_id", q)
+}
+
+func ListConstellations(filter ConstellationFilter, q UniverseQuery) ([]models.Constellation, int64, error) {
+	query := DB.Model(&models.Constellation{})
+	if len(filter.RegionIDs) > 0 {
+		query = query.Where("region_id IN ?", filter.RegionIDs)
+	}
+	return listUniverse[models.Constellation](query, "constellation_id", q)
+}
+
+func ListRegions(q UniverseQuery) ([]models.Region, int64, error) {
+	return listUniverse[models.Region](DB.Model(&models.Region{}), "region_id", q)
+}
+
+func ListESIItems(filter ItemFilter, q UniverseQuery) ([]models.ESIItem, int64, error) {
+	query := DB.Model(&models.ESIItem{})
+	if len(filter.GroupIDs) > 0 {
+		query = query.Where("group_id IN ?", filter.GroupIDs)
+	}
+	if filter.Published != nil {
+		query = query.Where("published = ?", *filter.Published)
+	}
+	return listUniverse[models.ESIItem](query, "type_id", q)
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// SystemLocation is a system with its constellation and region, as held by the universe cache.
+type SystemLocation struct {
+	SystemID          int
+	Name              string
+	SecurityClass     string
+	SecurityStatus    float64
+	SpaceClass        string
+	ConstellationID   int
+	ConstellationName string
+	RegionID          int
+	RegionName        string
+}
+
+// TypeInfo is an item type with its group and category, as held by the universe cache.
+// Descriptions are left out to keep the cache small.
+type TypeInfo struct {
+	TypeID     int
+	Name       string
+	Volume     float64
+	Published  bool
+	GroupID    int
+	GroupName  string
+	CategoryID int
+}
+
+// universeCache keeps the universe hierarchy in memory. Lookups read through to the database
+// on a miss; misses for rows that do not exist are not remembered. Complete is set while systems
+// holds every system rather than only those read through.
+type universeCache struct {
+	mu       sync.RWMutex
+	systems  map[int]SystemLocation
+	types    map[int]TypeInfo
+	complete bool
+}
+
+var universe = &universeCache{
+	systems: make(map[int]SystemLocation),
+	types:   make(map[int]TypeInfo),
+}
+
+const systemLocationQuery = `
+    SELECT systems.system_id, systems.name, systems.security_class, systems.security_status, systems.space_class,
+        systems.constellation_id, COALESCE(constellations.name, '') AS constellation_name,
+        COALESCE(constellations.region_id, 0) AS region_id, COALESCE(regions.name, '') AS region_name
+    FROM systems
+    LEFT JOIN constellations ON constellations.constellation_id = systems.constellation_id
+    LEFT JOIN regions ON regions.region_id = constellations.region_id`
+
+const typeInfoQuery = `
+    SELECT esi_items.type_id, esi_items.name, esi_items.volume, esi_items.published, esi_items.group_id,
+        COALESCE(item_groups.name, '') AS group_name, COALESCE(item_groups.category_id, 0) AS category_id
+    FROM esi_items
+    LEFT JOIN item_groups ON item_groups.group_id = esi_items.group_id`
+
+// LoadUniverseCache replaces the cached systems and types with the current database contents.
+// It runs at startup and whenever the universe data has been refreshed.
+func LoadUniverseCache() error {
+	var systems []SystemLocation
+	if err := DB.Raw(systemLocationQuery).Scan(&systems).Error; err != nil {
+		return err
+	}
+	var types []TypeInfo
+	if err := DB.Raw(typeInfoQuery).Scan(&types).Error; err != nil {
+		return err
+	}
+
+	systemMap := make(map[int]SystemLocation, len(systems))
+	for _, system := range systems {
+		systemMap[system.SystemID] = system
+	}
+	typeMap := make(map[int]TypeInfo, len(types))
+	for _, info := range types {
+		typeMap[info.TypeID] = info
+	}
+
+	universe.mu.Lock()
+	universe.systems = systemMap
+	universe.types = typeMap
+	universe.complete = true
+	universe.mu.Unlock()
+
+	log.Printf("Loaded universe cache with %d systems and %d types", len(systemMap), len(typeMap))
+	return nil
+}
+
+// InvalidateUniverseCache drops the cached universe data and reloads it, falling back to
+// reading through on demand if the reload fails.
+func InvalidateUniverseCache() {
+	universe.mu.Lock()
+	universe.systems = make(map[int]SystemLocation)
+	universe.types = make(map[int]TypeInfo)
+	universe.complete = false
+	universe.mu.Unlock()
+
+	if err := LoadUniverseCache(); err != nil {
+		log.Printf("Error reloading universe cache: %v", err)
+	}
+}
+
+// GetSystemLocation returns a system with its constellation and region. It returns
+// gorm.ErrRecordNotFound for unknown systems.
+func GetSystemLocation(systemID int) (SystemLocation, error) {
+	universe.mu.RLock()
+	location, ok := universe.systems[systemID]
+	universe.mu.RUnlock()
+	if ok {
+		return location, nil
+	}
+
+	result := DB.Raw(systemLocationQuery+" WHERE systems.system_id = ?", systemID).Scan(&location)
+	if result.Error != nil {
+		return location, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return location, gorm.ErrRecordNotFound
+	}
+
+	universe.mu.Lock()
+	universe.systems[systemID] = location
+	universe.mu.Unlock()
+	return location, nil
+}
+
+// systemIDsWhere returns the IDs of the systems that match, in ascending order. The cache is
+// loaded first when it does not hold every system.
+func systemIDsWhere(match func(SystemLocation) bool) ([]int, error) {
+	universe.mu.RLock()
+	complete := universe.complete
+	universe.mu.RUnlock()
+	if !complete {
+		if err := LoadUniverseCache(); err != nil {
+			return nil, err
+		}
+	}
+
+	systemIDs := []int{}
+	universe.mu.RLock()
+	for id, system := range universe.systems {
+		if match(system) {
+			systemIDs = append(systemIDs, id)
+		}
+	}
+	universe.mu.RUnlock()
+	sort.Ints(systemIDs)
+	return systemIDs, nil
+}
+
+// GetTypeInfos returns the known types among typeIDs, keyed by type ID.
+func GetTypeInfos(typeIDs []int) (map[int]TypeInfo, error) {
+	infos := make(map[int]TypeInfo, len(typeIDs))
+	var missing []int
+
+	universe.mu.RLock()
+	for _, id := range typeIDs {
+		if info, ok := universe.types[id]; ok {
+			infos[id] = info
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	universe.mu.RUnlock()
+
+	if len(missing) == 0 {
+		return infos, nil
+	}
+
+	var rows []TypeInfo
+	if err := DB.Raw(typeInfoQuery+" WHERE esi_items.type_id IN ?", missing).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	universe.mu.Lock()
+	for _, info := range rows {
+		universe.types[info.TypeID] = info
+		infos[info.TypeID] = info
+	}
+	universe.mu.Unlock()
+	return infos, nil
+}
+
+// GetTypeInfo returns a single type. It returns gorm.ErrRecordNotFound for unknown types.
+func GetTypeInfo(typeID int) (TypeInfo, error) {
+	infos, err := GetTypeInfos([]int{typeID})
+	if err != nil {
+		return TypeInfo{}, err
+	}
+	info, ok := infos[typeID]
+	if !ok {
+		return info, gorm.ErrRecordNotFound
+	}
+	return info, nil
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+var contentTypes = map[Format]string{
+	FormatJSON:    "application/json",
+	FormatCSV:     "text/csv",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// acceptAliases maps additional media types clients commonly send to a format.
+var acceptAliases = map[string]Format{
+	"application/jsonl":           FormatNDJSON,
+	"application/ndjson":          FormatNDJSON,
+	"application/x-parquet":       FormatParquet,
+	"application/parquet":         FormatParquet,
+	"application/csv":             FormatCSV,
+	"text/comma-separated-values": FormatCSV,
+}
+
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+// Tabular reports whether rows are flattened into columns for this format.
+func (f Format) Tabular() bool {
+	return f == FormatCSV || f == FormatParquet
+}
+
+// Negotiate picks the response format. An explicit format parameter wins over the Accept header;
+// anything unrecognized in the Accept header falls back to JSON.
+func Negotiate(formatParam, accept string) (Format, error) {
+	if formatParam != "" {
+		format := Format(strings.ToLower(formatParam))
+		if _, ok := contentTypes[format]; !ok {
+			return "", fmt.Errorf("Invalid format: expected one of json, csv, ndjson, parquet")
+		}
+		return format, nil
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		for format, contentType := range contentTypes {
+			if mediaType == contentType {
+				return format, nil
+			}
+		}
+		if format, ok := acceptAliases[mediaType]; ok {
+			return format, nil
+		}
+	}
+
+	return FormatJSON, nil
+}
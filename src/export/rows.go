@@ -0,0 +1,113 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// KillRow is a kill flattened into columns for tabular formats. Attacker lists are
+// semicolon separated; the final blow attacker gets dedicated columns.
+type KillRow struct {
+	KillmailID             int64     `parquet:"killmail_id"`
+	KillTime               time.Time `parquet:"kill_time,timestamp"`
+	CharacterID            int64     `parquet:"character_id"`
+	CorporationID          int64     `parquet:"corporation_id"`
+	AllianceID             int64     `parquet:"alliance_id"`
+	SolarSystemID          int64     `parquet:"solar_system_id"`
+	LocationID             int64     `parquet:"location_id"`
+	Hash                   string    `parquet:"hash"`
+	FittedValue            float64   `parquet:"fitted_value"`
+	DroppedValue           float64   `parquet:"dropped_value"`
+	DestroyedValue         float64   `parquet:"destroyed_value"`
+	TotalValue             float64   `parquet:"total_value"`
+	Points                 int64     `parquet:"points"`
+	NPC                    bool      `parquet:"npc"`
+	Solo                   bool      `parquet:"solo"`
+	Awox                   bool      `parquet:"awox"`
+	VictimCharacterID      int64     `parquet:"victim_character_id"`
+	VictimCorporationID    int64     `parquet:"victim_corporation_id"`
+	VictimAllianceID       int64     `parquet:"victim_alliance_id"`
+	VictimFactionID        int64     `parquet:"victim_faction_id"`
+	VictimShipTypeID       int64     `parquet:"victim_ship_type_id"`
+	VictimDamageTaken      int64     `parquet:"victim_damage_taken"`
+	VictimItemCount        int64     `parquet:"victim_item_count"`
+	AttackerCount          int64     `parquet:"attacker_count"`
+	FinalBlowCharacterID   int64     `parquet:"final_blow_character_id"`
+	FinalBlowCorporationID int64     `parquet:"final_blow_corporation_id"`
+	FinalBlowAllianceID    int64     `parquet:"final_blow_alliance_id"`
+	FinalBlowShipTypeID    int64     `parquet:"final_blow_ship_type_id"`
+	FinalBlowWeaponTypeID  int64     `parquet:"final_blow_weapon_type_id"`
+	AttackerCharacterIDs   string    `parquet:"attacker_character_ids"`
+	AttackerCorporationIDs string    `parquet:"attacker_corporation_ids"`
+	AttackerAllianceIDs    string    `parquet:"attacker_alliance_ids"`
+	AttackerShipTypeIDs    string    `parquet:"attacker_ship_type_ids"`
+}
+
+func NewKillRow(kill *models.Kill) KillRow {
+	row := KillRow{
+		KillmailID:          kill.KillmailID,
+		KillTime:            kill.KillTime,
+		CharacterID:         kill.CharacterID,
+		CorporationID:       kill.CorporationID,
+		AllianceID:          kill.AllianceID,
+		SolarSystemID:       int64(kill.SolarSystemID),
+		LocationID:          kill.LocationID,
+		Hash:                kill.Hash,
+		FittedValue:         kill.FittedValue,
+		DroppedValue:        kill.DroppedValue,
+		DestroyedValue:      kill.DestroyedValue,
+		TotalValue:          kill.TotalValue,
+		Points:              int64(kill.Points),
+		NPC:                 kill.NPC,
+		Solo:                kill.Solo,
+		Awox:                kill.Awox,
+		VictimCharacterID:   optionalID(kill.Victim.CharacterID),
+		VictimCorporationID: optionalID(kill.Victim.CorporationID),
+		VictimAllianceID:    optionalID(kill.Victim.AllianceID),
+		VictimFactionID:     optionalID(kill.Victim.FactionID),
+		VictimShipTypeID:    int64(kill.Victim.ShipTypeID),
+		VictimDamageTaken:   int64(kill.Victim.DamageTaken),
+		VictimItemCount:     int64(len(kill.Victim.Items)),
+		AttackerCount:       int64(len(kill.Attackers)),
+	}
+
+	var characters, corporations, alliances, ships []string
+	for _, attacker := range kill.Attackers {
+		if attacker.FinalBlow {
+			row.FinalBlowCharacterID = optionalID(attacker.CharacterID)
+			row.FinalBlowCorporationID = optionalID(attacker.CorporationID)
+			row.FinalBlowAllianceID = optionalID(attacker.AllianceID)
+			row.FinalBlowShipTypeID = int64(attacker.ShipTypeID)
+			row.FinalBlowWeaponTypeID = int64(attacker.WeaponTypeID)
+		}
+		characters = appendID(characters, attacker.CharacterID)
+		corporations = appendID(corporations, attacker.CorporationID)
+		alliances = appendID(alliances, attacker.AllianceID)
+		if attacker.ShipTypeID != 0 {
+			ships = append(ships, strconv.Itoa(attacker.ShipTypeID))
+		}
+	}
+	row.AttackerCharacterIDs = strings.Join(characters, ";")
+	row.AttackerCorporationIDs = strings.Join(corporations, ";")
+	row.AttackerAllianceIDs = strings.Join(alliances, ";")
+	row.AttackerShipTypeIDs = strings.Join(ships, ";")
+
+	return row
+}
+
+func optionalID(id *int) int64 {
+	if id == nil {
+		return 0
+	}
+	return int64(*id)
+}
+
+func appendID(ids []string, id *int) []string {
+	if id == nil {
+		return ids
+	}
+	return append(ids, strconv.Itoa(*id))
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize bounds how many rows are buffered before a row group is flushed.
+const parquetRowGroupSize = 10000
+
+// RowWriter streams rows of a single type in one export format.
+type RowWriter[T any] interface {
+	Write(row T) error
+	Close() error
+}
+
+func NewWriter[T any](format Format, w io.Writer) RowWriter[T] {
+	switch format {
+	case FormatCSV:
+		return &csvWriter[T]{w: csv.NewWriter(w)}
+	case FormatParquet:
+		return &parquetWriter[T]{w: parquet.NewGenericWriter[T](w, parquet.Compression(&parquet.Zstd))}
+	default:
+		return &ndjsonWriter[T]{enc: json.NewEncoder(w)}
+	}
+}
+
+type ndjsonWriter[T any] struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter[T]) Write(row T) error {
+	return w.enc.Encode(row)
+}
+
+func (w *ndjsonWriter[T]) Close() error {
+	return nil
+}
+
+// csvWriter writes a header derived from the parquet (or json) struct tags, then one record per row.
+type csvWriter[T any] struct {
+	w           *csv.Writer
+	wroteHeader bool
+	rows        int
+}
+
+func (w *csvWriter[T]) Write(row T) error {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if !w.wroteHeader {
+		if err := w.w.Write(columnNames(v.Type())); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+
+	record := make([]string, v.NumField())
+	for i := range record {
+		record[i] = formatValue(v.Field(i))
+	}
+	if err := w.w.Write(record); err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%1000 == 0 {
+		w.w.Flush()
+	}
+	return w.w.Error()
+}
+
+func (w *csvWriter[T]) Close() error {
+	if !w.wroteHeader {
+		var zero T
+		if err := w.w.Write(columnNames(reflect.Indirect(reflect.ValueOf(zero)).Type())); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func columnNames(t reflect.Type) []string {
+	names := make([]string, t.NumField())
+	for i := range names {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("parquet"), ",")[0]
+		if name == "" {
+			name = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[i] = name
+	}
+	return names
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+type parquetWriter[T any] struct {
+	w    *parquet.GenericWriter[T]
+	rows int
+}
+
+func (w *parquetWriter[T]) Write(row T) error {
+	if _, err := w.w.Write([]T{row}); err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%parquetRowGroupSize == 0 {
+		return w.w.Flush()
+	}
+	return nil
+}
+
+func (w *parquetWriter[T]) Close() error {
+	return w.w.Close()
+}
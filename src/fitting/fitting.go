@@ -0,0 +1,229 @@
+package fitting
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// Module is an item in one fitting section. In fitted slots it may carry the charge loaded into it.
+type Module struct {
+	Flag           int    `json:"flag"`
+	TypeID         int    `json:"type_id"`
+	Name           string `json:"name"`
+	Quantity       int    `json:"quantity"`
+	Destroyed      int    `json:"destroyed"`
+	Dropped        int    `json:"dropped"`
+	ChargeTypeID   int    `json:"charge_type_id,omitempty"`
+	ChargeName     string `json:"charge_name,omitempty"`
+	ChargeQuantity int    `json:"charge_quantity,omitempty"`
+}
+
+// Fitting is a victim's ship reconstructed from killmail items.
+type Fitting struct {
+	ShipTypeID int      `json:"ship_type_id"`
+	ShipName   string   `json:"ship_name"`
+	High       []Module `json:"high"`
+	Mid        []Module `json:"mid"`
+	Low        []Module `json:"low"`
+	Rig        []Module `json:"rig"`
+	Subsystem  []Module `json:"subsystem"`
+	DroneBay   []Module `json:"drone_bay"`
+	FighterBay []Module `json:"fighter_bay"`
+	Cargo      []Module `json:"cargo"`
+	Implant    []Module `json:"implant"`
+	Other      []Module `json:"other"`
+}
+
+func (f *Fitting) section(slot Slot) *[]Module {
+	switch slot {
+	case SlotHigh:
+		return &f.High
+	case SlotMid:
+		return &f.Mid
+	case SlotLow:
+		return &f.Low
+	case SlotRig:
+		return &f.Rig
+	case SlotSubsystem:
+		return &f.Subsystem
+	case SlotDroneBay:
+		return &f.DroneBay
+	case SlotFighterBay:
+		return &f.FighterBay
+	case SlotCargo:
+		return &f.Cargo
+	case SlotImplant:
+		return &f.Implant
+	default:
+		return &f.Other
+	}
+}
+
+// TypeIDs returns every type referenced by the victim, for resolving names.
+func TypeIDs(kill *models.Kill) []int {
+	ids := []int{kill.Victim.ShipTypeID}
+	for _, item := range kill.Victim.Items {
+		ids = append(ids, item.ItemTypeID)
+	}
+	return ids
+}
+
+// Decode rebuilds the victim's fitting. Killmails list a fitted module and its loaded charge
+// under the same flag, splitting either into several stacks when part of it dropped; stacks are
+// merged by type and the type with the smaller volume is taken to be the charge.
+func Decode(kill *models.Kill, types map[int]db.TypeInfo) Fitting {
+	f := Fitting{
+		ShipTypeID: kill.Victim.ShipTypeID,
+		ShipName:   typeName(types, kill.Victim.ShipTypeID),
+	}
+
+	byFlag := make(map[int][]models.Item)
+	var flags []int
+	for _, item := range kill.Victim.Items {
+		if _, ok := byFlag[item.Flag]; !ok {
+			flags = append(flags, item.Flag)
+		}
+		byFlag[item.Flag] = append(byFlag[item.Flag], item)
+	}
+	sort.Ints(flags)
+
+	for _, flag := range flags {
+		slot := SlotForFlag(flag)
+		section := f.section(slot)
+		items := byFlag[flag]
+
+		if isFittedSlot(slot) {
+			var stacks []Module
+			for _, item := range items {
+				stacks = mergeModule(stacks, newModule(item, types))
+			}
+			sort.SliceStable(stacks, func(i, j int) bool {
+				return types[stacks[i].TypeID].Volume > types[stacks[j].TypeID].Volume
+			})
+			module := stacks[0]
+			if len(stacks) > 1 {
+				charge := stacks[1]
+				module.ChargeTypeID = charge.TypeID
+				module.ChargeName = charge.Name
+				module.ChargeQuantity = charge.Quantity
+			}
+			*section = append(*section, module)
+			// Anything else listed under the slot is kept, as it is neither the module nor its charge.
+			for i := 2; i < len(stacks); i++ {
+				f.Other = mergeModule(f.Other, stacks[i])
+			}
+			continue
+		}
+
+		for _, item := range items {
+			*section = mergeModule(*section, newModule(item, types))
+		}
+	}
+
+	return f
+}
+
+func newModule(item models.Item, types map[int]db.TypeInfo) Module {
+	m := Module{Flag: item.Flag, TypeID: item.ItemTypeID, Name: typeName(types, item.ItemTypeID)}
+	if item.QuantityDestroyed != nil {
+		m.Destroyed = *item.QuantityDestroyed
+	}
+	if item.QuantityDropped != nil {
+		m.Dropped = *item.QuantityDropped
+	}
+	m.Quantity = m.Destroyed + m.Dropped
+	return m
+}
+
+// mergeModule adds quantities to an existing stack of the same type, as bays list drops and losses separately.
+func mergeModule(modules []Module, m Module) []Module {
+	for i := range modules {
+		if modules[i].TypeID == m.TypeID {
+			modules[i].Quantity += m.Quantity
+			modules[i].Destroyed += m.Destroyed
+			modules[i].Dropped += m.Dropped
+			return modules
+		}
+	}
+	return append(modules, m)
+}
+
+func typeName(types map[int]db.TypeInfo, typeID int) string {
+	if item, ok := types[typeID]; ok && item.Name != "" {
+		return item.Name
+	}
+	return fmt.Sprintf("Type %d", typeID)
+}
+
+// EFT renders the fitting in the EVE Fitting Tool text format understood by the game client and Pyfa.
+func (f Fitting) EFT(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s, %s]\n", f.ShipName, name)
+
+	for _, section := range [][]Module{f.Low, f.Mid, f.High, f.Rig, f.Subsystem} {
+		for _, m := range section {
+			b.WriteString(m.Name)
+			if m.ChargeName != "" {
+				b.WriteString(", " + m.ChargeName)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	for _, section := range [][]Module{f.DroneBay, f.FighterBay, f.Implant, f.Cargo} {
+		if len(section) == 0 {
+			continue
+		}
+		b.WriteString("\n")
+		for _, m := range section {
+			fmt.Fprintf(&b, "%s x%d\n", m.Name, m.Quantity)
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// DNA renders the fitting as a ship DNA string: ship, subsystems, high, mid, low, rigs,
+// drones and charges, each as typeID;quantity, terminated by "::".
+func (f Fitting) DNA() string {
+	parts := []string{strconv.Itoa(f.ShipTypeID)}
+	charges := make(map[int]int)
+	var chargeOrder []int
+
+	for _, section := range [][]Module{f.Subsystem, f.High, f.Mid, f.Low, f.Rig, f.DroneBay, f.FighterBay} {
+		counts := make(map[int]int)
+		var order []int
+		for _, m := range section {
+			if _, ok := counts[m.TypeID]; !ok {
+				order = append(order, m.TypeID)
+			}
+			if isFittedSlot(SlotForFlag(m.Flag)) {
+				counts[m.TypeID]++
+			} else {
+				counts[m.TypeID] += m.Quantity
+			}
+
+			if m.ChargeTypeID != 0 {
+				if _, ok := charges[m.ChargeTypeID]; !ok {
+					chargeOrder = append(chargeOrder, m.ChargeTypeID)
+				}
+				charges[m.ChargeTypeID] += m.ChargeQuantity
+			}
+		}
+		for _, typeID := range order {
+			parts = append(parts, fmt.Sprintf("%d;%d", typeID, counts[typeID]))
+		}
+	}
+
+	for _, typeID := range chargeOrder {
+		parts = append(parts, fmt.Sprintf("%d;%d", typeID, charges[typeID]))
+	}
+
+	return strings.Join(parts, ":") + "::"
+}
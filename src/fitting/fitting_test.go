@@ -0,0 +1,186 @@
+package fitting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+const (
+	rifter            = 587
+	autocannon        = 2889
+	empS              = 12625
+	damageControl     = 2048
+	warrior           = 2488
+	nanite            = 28668
+	flagHigh0         = 27
+	flagHigh1         = 28
+	flagLow0          = 11
+	rifterName        = "Rifter"
+	autocannonName    = "200mm AutoCannon II"
+	empSName          = "EMP S"
+	damageControlName = "Damage Control II"
+	warriorName       = "Warrior II"
+	naniteName        = "Nanite Repair Paste"
+)
+
+var testTypes = map[int]db.TypeInfo{
+	rifter:        {TypeID: rifter, Name: rifterName, Volume: 27289},
+	autocannon:    {TypeID: autocannon, Name: autocannonName, Volume: 5},
+	empS:          {TypeID: empS, Name: empSName, Volume: 0.0025},
+	damageControl: {TypeID: damageControl, Name: damageControlName, Volume: 5},
+	warrior:       {TypeID: warrior, Name: warriorName, Volume: 5},
+	nanite:        {TypeID: nanite, Name: naniteName, Volume: 0.001},
+}
+
+func destroyed(typeID, flag, quantity int) models.Item {
+	return models.Item{ItemTypeID: typeID, Flag: flag, QuantityDestroyed: &quantity}
+}
+
+func dropped(typeID, flag, quantity int) models.Item {
+	return models.Item{ItemTypeID: typeID, Flag: flag, QuantityDropped: &quantity}
+}
+
+func testKill(items ...models.Item) *models.Kill {
+	return &models.Kill{Victim: models.Victim{ShipTypeID: rifter, Items: items}}
+}
+
+// rifterKill has a charge split into destroyed and dropped stacks, and drones lost and dropped.
+func rifterKill() *models.Kill {
+	return testKill(
+		destroyed(damageControl, flagLow0, 1),
+		destroyed(autocannon, flagHigh0, 1),
+		destroyed(empS, flagHigh0, 100),
+		dropped(empS, flagHigh0, 50),
+		dropped(autocannon, flagHigh1, 1),
+		destroyed(warrior, flagDroneBay, 2),
+		dropped(warrior, flagDroneBay, 3),
+		dropped(empS, flagCargo, 200),
+	)
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		name string
+		kill *models.Kill
+		want Fitting
+	}{
+		{
+			name: "module alone",
+			kill: testKill(destroyed(autocannon, flagHigh0, 1)),
+			want: Fitting{
+				ShipTypeID: rifter, ShipName: rifterName,
+				High: []Module{{Flag: flagHigh0, TypeID: autocannon, Name: autocannonName, Quantity: 1, Destroyed: 1}},
+			},
+		},
+		{
+			name: "charge stacks merged",
+			kill: testKill(
+				dropped(empS, flagHigh0, 50),
+				destroyed(autocannon, flagHigh0, 1),
+				destroyed(empS, flagHigh0, 100),
+			),
+			want: Fitting{
+				ShipTypeID: rifter, ShipName: rifterName,
+				High: []Module{{
+					Flag: flagHigh0, TypeID: autocannon, Name: autocannonName, Quantity: 1, Destroyed: 1,
+					ChargeTypeID: empS, ChargeName: empSName, ChargeQuantity: 150,
+				}},
+			},
+		},
+		{
+			name: "third type kept",
+			kill: testKill(
+				destroyed(autocannon, flagHigh0, 1),
+				destroyed(empS, flagHigh0, 100),
+				dropped(nanite, flagHigh0, 5),
+			),
+			want: Fitting{
+				ShipTypeID: rifter, ShipName: rifterName,
+				High: []Module{{
+					Flag: flagHigh0, TypeID: autocannon, Name: autocannonName, Quantity: 1, Destroyed: 1,
+					ChargeTypeID: empS, ChargeName: empSName, ChargeQuantity: 100,
+				}},
+				Other: []Module{{Flag: flagHigh0, TypeID: nanite, Name: naniteName, Quantity: 5, Dropped: 5}},
+			},
+		},
+		{
+			name: "bay stacks merged",
+			kill: testKill(destroyed(warrior, flagDroneBay, 2), dropped(warrior, flagDroneBay, 3)),
+			want: Fitting{
+				ShipTypeID: rifter, ShipName: rifterName,
+				DroneBay: []Module{{Flag: flagDroneBay, TypeID: warrior, Name: warriorName, Quantity: 5, Destroyed: 2, Dropped: 3}},
+			},
+		},
+		{
+			name: "unknown type",
+			kill: &models.Kill{Victim: models.Victim{ShipTypeID: 1, Items: []models.Item{destroyed(2, flagCargo, 1)}}},
+			want: Fitting{
+				ShipTypeID: 1, ShipName: "Type 1",
+				Cargo: []Module{{Flag: flagCargo, TypeID: 2, Name: "Type 2", Quantity: 1, Destroyed: 1}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		if got := Decode(tc.kill, testTypes); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Decode = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestEFT(t *testing.T) {
+	cases := []struct {
+		name string
+		kill *models.Kill
+		want string
+	}{
+		{
+			name: "hull only",
+			kill: testKill(),
+			want: "[Rifter, Loss]\n",
+		},
+		{
+			name: "fitted with bays",
+			kill: rifterKill(),
+			want: "[Rifter, Loss]\n" +
+				"Damage Control II\n\n\n" +
+				"200mm AutoCannon II, EMP S\n200mm AutoCannon II\n\n\n\n\n" +
+				"Warrior II x5\n\n" +
+				"EMP S x200\n",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := Decode(tc.kill, testTypes).EFT("Loss"); got != tc.want {
+			t.Errorf("%s: EFT = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDNA(t *testing.T) {
+	cases := []struct {
+		name string
+		kill *models.Kill
+		want string
+	}{
+		{
+			name: "hull only",
+			kill: testKill(),
+			want: "587::",
+		},
+		{
+			name: "fitted with bays",
+			kill: rifterKill(),
+			want: "587:2889;2:2048;1:2488;5:12625;150::",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := Decode(tc.kill, testTypes).DNA(); got != tc.want {
+			t.Errorf("%s: DNA = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package fitting
+
+// Slot groups inventory flags into the sections of a fitting window.
+type Slot string
+
+const (
+	SlotHigh       Slot = "high"
+	SlotMid        Slot = "mid"
+	SlotLow        Slot = "low"
+	SlotRig        Slot = "rig"
+	SlotSubsystem  Slot = "subsystem"
+	SlotDroneBay   Slot = "drone_bay"
+	SlotFighterBay Slot = "fighter_bay"
+	SlotCargo      Slot = "cargo"
+	SlotImplant    Slot = "implant"
+	SlotOther      Slot = "other"
+)
+
+// Inventory flags as used by ESI killmails.
+const (
+	flagCargo            = 5
+	flagLoSlot0          = 11
+	flagLoSlot7          = 18
+	flagMedSlot0         = 19
+	flagMedSlot7         = 26
+	flagHiSlot0          = 27
+	flagHiSlot7          = 34
+	flagDroneBay         = 87
+	flagBooster          = 88
+	flagImplant          = 89
+	flagRigSlot0         = 92
+	flagRigSlot7         = 99
+	flagSubSystemSlot0   = 125
+	flagSubSystemSlot7   = 132
+	flagSpecializedHold0 = 133
+	flagSpecializedHold1 = 151
+	flagQuafeBay         = 154
+	flagFleetHangar      = 155
+	flagFighterBay       = 158
+	flagFighterTube0     = 159
+	flagFighterTube4     = 163
+	flagSubSystemBay     = 177
+	flagInfrastructure   = 179
+)
+
+// SlotForFlag maps an inventory flag to the fitting section it belongs to.
+func SlotForFlag(flag int) Slot {
+	switch {
+	case flag >= flagHiSlot0 && flag <= flagHiSlot7:
+		return SlotHigh
+	case flag >= flagMedSlot0 && flag <= flagMedSlot7:
+		return SlotMid
+	case flag >= flagLoSlot0 && flag <= flagLoSlot7:
+		return SlotLow
+	case flag >= flagRigSlot0 && flag <= flagRigSlot7:
+		return SlotRig
+	case flag >= flagSubSystemSlot0 && flag <= flagSubSystemSlot7:
+		return SlotSubsystem
+	case flag == flagDroneBay:
+		return SlotDroneBay
+	case flag == flagFighterBay || (flag >= flagFighterTube0 && flag <= flagFighterTube4):
+		return SlotFighterBay
+	case flag == flagImplant || flag == flagBooster:
+		return SlotImplant
+	case flag == flagCargo,
+		flag >= flagSpecializedHold0 && flag <= flagSpecializedHold1,
+		flag == flagQuafeBay, flag == flagFleetHangar, flag == flagSubSystemBay, flag == flagInfrastructure:
+		return SlotCargo
+	default:
+		return SlotOther
+	}
+}
+
+// isFittedSlot reports whether a slot holds one module per flag, possibly with a loaded charge.
+func isFittedSlot(slot Slot) bool {
+	switch slot {
+	case SlotHigh, SlotMid, SlotLow, SlotRig, SlotSubsystem:
+		return true
+	}
+	return false
+}
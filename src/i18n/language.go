@@ -0,0 +1,89 @@
+// Package i18n selects the language universe names are served in.
+package i18n
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the language of the names stored on the universe tables themselves.
+const Default = "en"
+
+// supported are the languages ESI serves universe names in.
+var supported = map[string]bool{
+	"en": true, "de": true, "fr": true, "ja": true, "ru": true, "zh": true, "ko": true, "es": true,
+}
+
+// Languages returns the configured languages from UNIVERSE_LANGUAGES (comma separated,
+// e.g. "en,de,ru"). Unsupported entries are ignored and the default is always included.
+func Languages() []string {
+	languages := []string{Default}
+	seen := map[string]bool{Default: true}
+	for _, lang := range strings.Split(os.Getenv("UNIVERSE_LANGUAGES"), ",") {
+		lang = normalize(lang)
+		if supported[lang] && !seen[lang] {
+			seen[lang] = true
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// Localized returns the configured languages other than the default, which need separate storage.
+func Localized() []string {
+	return Languages()[1:]
+}
+
+// Negotiate picks the response language from an explicit lang parameter, falling back to the
+// Accept-Language header and finally the default. Only configured languages are chosen.
+func Negotiate(langParam, acceptLanguage string) string {
+	configured := make(map[string]bool)
+	for _, lang := range Languages() {
+		configured[lang] = true
+	}
+
+	if lang := normalize(langParam); configured[lang] {
+		return lang
+	}
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		lang := normalize(fields[0])
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		candidates = append(candidates, candidate{lang, q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.q > 0 && configured[c.lang] {
+			return c.lang
+		}
+	}
+	return Default
+}
+
+// normalize reduces a language tag such as "de-DE" to its primary subtag.
+func normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
@@ -0,0 +1,342 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/services"
+)
+
+const importChunkSize = 1000
+
+// ImportOptions configures a bulk import from zKillboard history dumps.
+//
+// Dir is expected to contain history/YYYYMMDD.json files as served by zKillboard's
+// /api/history/ endpoint, and optionally killmails/<killmail_id>.json ESI killmail archives.
+type ImportOptions struct {
+	Dir          string
+	From         time.Time
+	To           time.Time
+	Concurrency  int
+	FetchMissing bool
+}
+
+// RunHistoryImport imports every history file in range, keeping only kills in which a tracked
+// character, corporation or alliance took part. Progress is stored per file after each chunk.
+func RunHistoryImport(opts ImportOptions) error {
+	files, err := historyFiles(opts)
+	if err != nil {
+		return err
+	}
+	log.Printf("Found %d history files to import", len(files))
+
+	tracked, err := loadTrackedSet()
+	if err != nil {
+		return err
+	}
+	if tracked.empty() {
+		return fmt.Errorf("no tracked characters, corporations or alliances to import kills for")
+	}
+
+	for _, file := range files {
+		if err := importHistoryFile(file, tracked, opts); err != nil {
+			return fmt.Errorf("error importing %s: %v", filepath.Base(file), err)
+		}
+	}
+
+	log.Println("Finished history import")
+	return nil
+}
+
+func historyFiles(opts ImportOptions) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(opts.Dir, "history", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range paths {
+		date, err := historyFileDate(path)
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			continue
+		}
+		if !opts.From.IsZero() && date.Before(opts.From) {
+			continue
+		}
+		if !opts.To.IsZero() && date.After(opts.To) {
+			continue
+		}
+		files = append(files, path)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func historyFileDate(path string) (time.Time, error) {
+	name := strings.TrimSuffix(filepath.Base(path), ".json")
+	if date, err := time.Parse("20060102", name); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", name)
+}
+
+func importHistoryFile(path string, tracked trackedSet, opts ImportOptions) error {
+	name := filepath.Base(path)
+	progress, err := db.GetImportProgress(name)
+	if err != nil {
+		return err
+	}
+	if progress.CompletedAt != nil {
+		log.Printf("Skipping %s, already imported", name)
+		return nil
+	}
+
+	hashes, err := readHistoryFile(path)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(hashes))
+	for id := range hashes {
+		if id > progress.LastKillmailID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	progress.TotalKills = len(hashes)
+	log.Printf("Importing %s: %d killmails, %d remaining", name, len(hashes), len(ids))
+
+	// After a killmail fails to load, the rest of the file is still imported, but the cursor
+	// stays before it and the file is left incomplete, so the next run retries from there.
+	held := false
+	failures := 0
+	for start := 0; start < len(ids); start += importChunkSize {
+		end := start + importChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		kills, failed := hydrateKillmails(chunk, hashes, tracked, opts)
+		if err := db.ImportKills(kills); err != nil {
+			return err
+		}
+		failures += len(failed)
+		if held {
+			continue
+		}
+
+		stored := chunk
+		if len(failed) > 0 {
+			held = true
+			first := failed[0]
+			for _, id := range failed {
+				first = min(first, id)
+			}
+			stored = chunk[:sort.Search(len(chunk), func(i int) bool { return chunk[i] >= first })]
+		}
+		if len(stored) == 0 {
+			continue
+		}
+
+		last := stored[len(stored)-1]
+		progress.ProcessedKills += len(stored)
+		for i := range kills {
+			if kills[i].KillmailID <= last {
+				progress.ImportedKills++
+			}
+		}
+		progress.LastKillmailID = last
+		if err := db.SaveImportProgress(progress); err != nil {
+			return err
+		}
+	}
+
+	if held {
+		log.Printf("Imported %s partially: %d killmails failed to load and will be retried from killmail %d on the next run",
+			name, failures, progress.LastKillmailID)
+		return nil
+	}
+
+	now := time.Now()
+	progress.CompletedAt = &now
+	if err := db.SaveImportProgress(progress); err != nil {
+		return err
+	}
+
+	log.Printf("Finished %s: imported %d of %d killmails", name, progress.ImportedKills, progress.TotalKills)
+	return nil
+}
+
+func readHistoryFile(path string) (map[int64]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[int64]string, len(raw))
+	for key, hash := range raw {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid killmail ID %q", key)
+		}
+		hashes[id] = hash
+	}
+	return hashes, nil
+}
+
+// hydrateKillmails loads the ESI killmail for each ID, from the local archive when present and
+// from ESI otherwise, and returns those attributed to a tracked entity along with the IDs that
+// failed to load.
+func hydrateKillmails(ids []int64, hashes map[int64]string, tracked trackedSet, opts ImportOptions) ([]models.Kill, []int64) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, opts.Concurrency)
+	var kills []models.Kill
+	var failed []int64
+
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			kill, err := loadKillmail(opts, id, hashes[id])
+			if err != nil {
+				log.Printf("Error loading killmail %d: %v", id, err)
+				mu.Lock()
+				failed = append(failed, id)
+				mu.Unlock()
+				return
+			}
+			if kill == nil {
+				return
+			}
+
+			entityType, entityID, ok := tracked.match(kill)
+			if !ok {
+				return
+			}
+
+			kill.KillmailID = id
+			kill.Hash = hashes[id]
+			if entityType == models.EntityTypeCharacter {
+				kill.CharacterID = entityID
+			}
+			kill.ResolveAffiliation(entityType, entityID)
+
+			mu.Lock()
+			kills = append(kills, *kill)
+			mu.Unlock()
+		}(id)
+	}
+
+	wg.Wait()
+	return kills, failed
+}
+
+// loadKillmail returns nil without an error when the killmail is not archived and fetching is disabled.
+func loadKillmail(opts ImportOptions, id int64, hash string) (*models.Kill, error) {
+	data, err := os.ReadFile(filepath.Join(opts.Dir, "killmails", fmt.Sprintf("%d.json", id)))
+	if err == nil {
+		var kill models.Kill
+		if err := json.Unmarshal(data, &kill); err != nil {
+			return nil, err
+		}
+		return &kill, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if !opts.FetchMissing {
+		return nil, nil
+	}
+	return services.FetchKillmailFromESI(id, hash)
+}
+
+// trackedSet holds the IDs of everything whose kills are stored.
+type trackedSet struct {
+	characters   map[int64]bool
+	corporations map[int64]bool
+	alliances    map[int64]bool
+}
+
+func loadTrackedSet() (trackedSet, error) {
+	set := trackedSet{
+		characters:   make(map[int64]bool),
+		corporations: make(map[int64]bool),
+		alliances:    make(map[int64]bool),
+	}
+
+	characters, err := db.GetAllCharacters()
+	if err != nil {
+		return set, err
+	}
+	for _, character := range characters {
+		set.characters[character.ID] = true
+	}
+
+	entities, err := db.GetTrackedEntities("")
+	if err != nil {
+		return set, err
+	}
+	for _, entity := range entities {
+		switch entity.EntityType {
+		case models.EntityTypeCorporation:
+			set.corporations[entity.EntityID] = true
+		case models.EntityTypeAlliance:
+			set.alliances[entity.EntityID] = true
+		}
+	}
+
+	return set, nil
+}
+
+func (s trackedSet) empty() bool {
+	return len(s.characters) == 0 && len(s.corporations) == 0 && len(s.alliances) == 0
+}
+
+// match returns the tracked entity a kill should be attributed to. Tracked characters take
+// precedence over corporations, and corporations over alliances.
+func (s trackedSet) match(kill *models.Kill) (string, int64, bool) {
+	var corporationID, allianceID int64
+	for _, attacker := range kill.Attackers {
+		if attacker.CharacterID != nil && s.characters[int64(*attacker.CharacterID)] {
+			return models.EntityTypeCharacter, int64(*attacker.CharacterID), true
+		}
+		if corporationID == 0 && attacker.CorporationID != nil && s.corporations[int64(*attacker.CorporationID)] {
+			corporationID = int64(*attacker.CorporationID)
+		}
+		if allianceID == 0 && attacker.AllianceID != nil && s.alliances[int64(*attacker.AllianceID)] {
+			allianceID = int64(*attacker.AllianceID)
+		}
+	}
+
+	if corporationID != 0 {
+		return models.EntityTypeCorporation, corporationID, true
+	}
+	if allianceID != 0 {
+		return models.EntityTypeAlliance, allianceID, true
+	}
+	return "", 0, false
+}
@@ -16,6 +16,11 @@ import (
 	"github.com/tadeasf/eve-ran/src/services"
 )
 
+const (
+	killBatchSize     = 100
+	killBatchInterval = 2 * time.Second
+)
+
 func StartKillFetcherJob() {
 	c := cron.New()
 	c.AddFunc("@every 1h", func() {
@@ -41,16 +46,38 @@ func fetchKillsForAllCharacters() {
 	}
 
 	log.Println("Finished fetching kills for all characters")
+
+	fetchKillsForAllTrackedEntities()
+}
+
+func fetchKillsForAllTrackedEntities() {
+	entities, err := db.GetTrackedEntities("")
+	if err != nil {
+		log.Printf("Error fetching tracked entities: %v", err)
+		return
+	}
+
+	log.Printf("Found %d tracked corporations and alliances", len(entities))
+
+	for _, entity := range entities {
+		fetchKillsForEntity(entity.EntityType, entity.EntityID)
+	}
+
+	log.Println("Finished fetching kills for all tracked entities")
 }
 
 func fetchKillsForCharacter(characterID int64) {
-	lastKillTime, err := db.GetLastKillTimeForCharacter(characterID)
+	fetchKillsForEntity(models.EntityTypeCharacter, characterID)
+}
+
+func fetchKillsForEntity(entityType string, entityID int64) {
+	lastKillTime, err := db.GetLastKillTimeForEntity(entityType, entityID)
 	if err != nil {
-		log.Printf("Error getting last kill time for character %d: %v", characterID, err)
+		log.Printf("Error getting last kill time for %s %d: %v", entityType, entityID, err)
 		lastKillTime = time.Time{}
 	}
-	log.Printf("Last kill time for character %d: %v", characterID, lastKillTime)
-	isNewCharacter := lastKillTime.IsZero()
+	log.Printf("Last kill time for %s %d: %v", entityType, entityID, lastKillTime)
+	isNewEntity := lastKillTime.IsZero()
 	page := 1
 	totalNewKills := 0
 
@@ -58,33 +85,40 @@ func fetchKillsForCharacter(characterID int64) {
 	semaphore := make(chan struct{}, maxConcurrentRequests)
 	var wg sync.WaitGroup
 
-	killChan := make(chan *models.Kill, maxConcurrentRequests)
-	done := make(chan bool)
 	stopProcessing := make(chan bool)
 
-	go func() {
-		for kill := range killChan {
-			err = db.UpsertKill(kill)
-			if err != nil {
-				log.Printf("Error upserting kill %d: %v", kill.KillmailID, err)
-			} else {
-				totalNewKills++
+	// Kills are written in transactions of killBatchSize as the ESI lookups complete.
+	// Writes are serialized by the writer, so the counter needs no further locking.
+	writer := db.NewBatchWriter(killBatchSize, killBatchInterval, func(kills []models.Kill) error {
+		err := db.UpsertKills(kills)
+		if err == nil {
+			totalNewKills += len(kills)
+			return nil
+		}
+
+		// One bad row fails the whole transaction, so write the batch again a kill at a time.
+		log.Printf("Error upserting %d kills for %s %d, retrying one by one: %v", len(kills), entityType, entityID, err)
+		for i := range kills {
+			if err := db.UpsertKill(&kills[i]); err != nil {
+				log.Printf("Error upserting kill %d for %s %d: %v", kills[i].KillmailID, entityType, entityID, err)
+				continue
 			}
+			totalNewKills++
 		}
-		done <- true
-	}()
+		return nil
+	})
 
 outerLoop:
 	for {
-		log.Printf("Fetching page %d for character %d", page, characterID)
-		kills, err := services.FetchKillsFromZKillboard(characterID, page)
+		log.Printf("Fetching page %d for %s %d", page, entityType, entityID)
+		kills, err := services.FetchEntityKillsFromZKillboard(entityType, entityID, page)
 		if err != nil {
-			log.Printf("Error fetching kills for character %d: %v", characterID, err)
+			log.Printf("Error fetching kills for %s %d: %v", entityType, entityID, err)
 			break
 		}
 
 		if len(kills) == 0 {
-			log.Printf("No more kills found for character %d", characterID)
+			log.Printf("No more kills found for %s %d", entityType, entityID)
 			break
 		}
 
@@ -100,22 +134,18 @@ outerLoop:
 					semaphore <- struct{}{}
 					defer func() { <-semaphore }()
 
-					esiKill, err := services.FetchKillmailFromESI(k.KillmailID, k.Hash)
-					if err != nil {
+					if err := hydrateKill(&k, entityType, entityID); err != nil {
 						log.Printf("Error fetching ESI killmail %d: %v", k.KillmailID, err)
 						return
 					}
 
-					k.KillTime = esiKill.KillTime
-					k.SolarSystemID = esiKill.SolarSystemID
-					k.Victim = esiKill.Victim
-					k.Attackers = esiKill.Attackers
-
-					if isNewCharacter || k.KillTime.After(lastKillTime) {
+					if isNewEntity || k.KillTime.After(lastKillTime) {
 						atomic.AddInt32(&newKills, 1)
-						killChan <- &k
+						if err := writer.Add(k); err != nil {
+							log.Printf("Error upserting kills for %s %d: %v", entityType, entityID, err)
+						}
 					} else {
-						log.Printf("Reached already processed kills for character %d", characterID)
+						log.Printf("Reached already processed kills for %s %d", entityType, entityID)
 						stopProcessing <- true
 					}
 				}(kill)
@@ -124,10 +154,10 @@ outerLoop:
 
 		wg.Wait()
 
-		log.Printf("Processed %d new kills for character %d on page %d", newKills, characterID, page)
+		log.Printf("Processed %d new kills for %s %d on page %d", newKills, entityType, entityID, page)
 
-		if newKills == 0 && !isNewCharacter {
-			log.Printf("No new kills on page %d for character %d, stopping", page, characterID)
+		if newKills == 0 && !isNewEntity {
+			log.Printf("No new kills on page %d for %s %d, stopping", page, entityType, entityID)
 			break
 		}
 
@@ -135,10 +165,67 @@ outerLoop:
 		time.Sleep(1 * time.Second)
 	}
 
-	close(killChan)
-	<-done
+	if err := writer.Close(); err != nil {
+		log.Printf("Error upserting kills for %s %d: %v", entityType, entityID, err)
+	}
 
-	log.Printf("Finished fetching kills for character %d. Total new kills: %d", characterID, totalNewKills)
+	log.Printf("Finished fetching kills for %s %d. Total new kills: %d", entityType, entityID, totalNewKills)
+}
+
+// hydrateKill fills a zKillboard list row from its ESI killmail and attributes it to the given entity.
+func hydrateKill(k *models.Kill, entityType string, entityID int64) error {
+	esiKill, err := services.FetchKillmailFromESI(k.KillmailID, k.Hash)
+	if err != nil {
+		return err
+	}
+
+	k.KillTime = esiKill.KillTime
+	k.SolarSystemID = esiKill.SolarSystemID
+	k.Victim = esiKill.Victim
+	k.Attackers = esiKill.Attackers
+	if entityType == models.EntityTypeCharacter {
+		k.CharacterID = entityID
+	}
+	k.ResolveAffiliation(entityType, entityID)
+	return nil
+}
+
+// HydrateKills fills zKillboard list rows from their ESI killmails and attributes them to the
+// given entity. Kills whose killmail cannot be fetched are logged and left out.
+func HydrateKills(kills []models.Kill, entityType string, entityID int64) []models.Kill {
+	const maxConcurrentRequests = 10
+	semaphore := make(chan struct{}, maxConcurrentRequests)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var hydrated []models.Kill
+
+	for _, kill := range kills {
+		wg.Add(1)
+		go func(k models.Kill) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if err := hydrateKill(&k, entityType, entityID); err != nil {
+				log.Printf("Error fetching ESI killmail %d: %v", k.KillmailID, err)
+				return
+			}
+
+			mu.Lock()
+			hydrated = append(hydrated, k)
+			mu.Unlock()
+		}(kill)
+	}
+
+	wg.Wait()
+	return hydrated
+}
+
+// FetchAllKillsForEntity runs a full kill fetch for a newly tracked corporation or alliance.
+func FetchAllKillsForEntity(entityType string, entityID int64) {
+	log.Printf("Starting full kill fetch for %s %d", entityType, entityID)
+	fetchKillsForEntity(entityType, entityID)
+	log.Printf("Finished full kill fetch for %s %d", entityType, entityID)
 }
 
 func FetchAllKillsForCharacter(characterID int64) {
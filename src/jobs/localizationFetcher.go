@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/i18n"
+	"github.com/tadeasf/eve-ran/src/services"
+)
+
+var localizedKinds = []string{
+	models.LocalizedRegion,
+	models.LocalizedConstellation,
+	models.LocalizedSystem,
+	models.LocalizedType,
+}
+
+// fetchAndUpdateLocalizedNames fetches names in every configured non-English language for
+// universe objects that do not have one yet.
+func fetchAndUpdateLocalizedNames() {
+	languages := i18n.Localized()
+	if len(languages) == 0 {
+		return
+	}
+	log.Printf("Fetching localized names for %v", languages)
+
+	writer := db.NewBatchWriter(universeBatchSize, universeBatchInterval, db.UpsertLocalizedNames)
+
+	for _, language := range languages {
+		for _, kind := range localizedKinds {
+			ids, err := db.GetUnlocalizedIDs(kind, language)
+			if err != nil {
+				log.Printf("Error loading %s IDs missing %s names: %v", kind, language, err)
+				continue
+			}
+
+			var wg sync.WaitGroup
+			semaphore := make(chan struct{}, 20)
+			for _, id := range ids {
+				wg.Add(1)
+				go func(id int) {
+					defer wg.Done()
+					semaphore <- struct{}{}
+					defer func() { <-semaphore }()
+
+					name, err := services.FetchLocalizedName(kind, id, language)
+					if err != nil {
+						log.Printf("Error fetching %s name of %s %d: %v", language, kind, id, err)
+						return
+					}
+					if err := writer.Add(*name); err != nil {
+						log.Printf("Error storing localized names: %v", err)
+					}
+				}(id)
+			}
+			wg.Wait()
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Error storing localized names: %v", err)
+	}
+	log.Println("Finished fetching localized names")
+}
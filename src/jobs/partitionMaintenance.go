@@ -0,0 +1,236 @@
+package jobs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+const (
+	// partitionsAhead is how many months of empty partitions are kept ready.
+	partitionsAhead     = 2
+	defaultArchiveDir   = "archives"
+	restoreBatchSize    = 1000
+	maxArchiveLineBytes = 16 << 20
+)
+
+// StartPartitionMaintenanceJob creates upcoming kill partitions and applies the retention
+// policy daily.
+func StartPartitionMaintenanceJob() {
+	c := cron.New()
+	c.AddFunc("@daily", MaintainKillPartitions)
+	c.Start()
+
+	go MaintainKillPartitions()
+}
+
+func MaintainKillPartitions() {
+	if err := db.EnsureKillPartitions(partitionsAhead); err != nil {
+		log.Printf("Error creating kill partitions: %v", err)
+	}
+
+	months := retentionMonths()
+	if months == 0 {
+		db.SetKillRetention(time.Time{})
+		return
+	}
+	cutoff := time.Now().UTC().AddDate(0, -months, 0)
+	db.SetKillRetention(cutoff)
+	if err := ArchiveKillsBefore(cutoff, archiveDir()); err != nil {
+		log.Printf("Error archiving kill partitions: %v", err)
+	}
+}
+
+// retentionMonths reads KILL_RETENTION_MONTHS, the number of whole months of kills kept in
+// the database besides the current one; 0 keeps everything.
+func retentionMonths() int {
+	value := os.Getenv("KILL_RETENTION_MONTHS")
+	if value == "" {
+		return 0
+	}
+	months, err := strconv.Atoi(value)
+	if err != nil || months < 0 {
+		log.Printf("Invalid KILL_RETENTION_MONTHS %q, keeping all kills", value)
+		return 0
+	}
+	return months
+}
+
+// archiveDir reads KILL_ARCHIVE_DIR, where archived partitions are written.
+func archiveDir() string {
+	if dir := os.Getenv("KILL_ARCHIVE_DIR"); dir != "" {
+		return dir
+	}
+	return defaultArchiveDir
+}
+
+// ArchiveKillsBefore writes the partition of every month before cutoff's month to
+// <dir>/<partition>.ndjson.gz and drops it once the archive is safely on disk. A month archived
+// before, then written again, as by a restore, goes to <dir>/<partition>.<n>.ndjson.gz.
+func ArchiveKillsBefore(cutoff time.Time, dir string) error {
+	partitions, err := db.GetKillPartitions()
+	if err != nil {
+		return err
+	}
+
+	cutoff = time.Date(cutoff.Year(), cutoff.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, p := range partitions {
+		if p.To.After(cutoff) {
+			break
+		}
+
+		path, count, err := archivePartition(p, dir)
+		if err != nil {
+			return fmt.Errorf("error archiving %s: %v", p.Name, err)
+		}
+		if err := db.DropKillPartition(p); err != nil {
+			return fmt.Errorf("error dropping %s: %v", p.Name, err)
+		}
+		log.Printf("Archived %d kills of %s to %s", count, p.Name, path)
+	}
+	return nil
+}
+
+// archivePartition writes a partition as gzipped NDJSON, one models.Kill per line. The file is
+// written under a temporary name and linked to its final name when complete, so a crash never
+// leaves a truncated archive behind and an existing archive is never replaced.
+func archivePartition(p db.KillPartition, dir string) (string, int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp(dir, p.Name+".*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	encoder := json.NewEncoder(gz)
+	count := 0
+	err = db.StreamPartitionKills(p, func(kill *models.Kill) error {
+		count++
+		return encoder.Encode(kill)
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	path, err := linkArchive(tmp.Name(), dir, p.Name)
+	return path, count, err
+}
+
+// linkArchive gives a complete archive the first free name of <dir>/<name>[.<n>].ndjson.gz.
+// Unlike a rename, a link fails rather than replace an existing file.
+func linkArchive(tmp, dir, name string) (string, error) {
+	for n := 1; ; n++ {
+		path := filepath.Join(dir, name+".ndjson.gz")
+		if n > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s.%d.ndjson.gz", name, n))
+		}
+		err := os.Link(tmp, path)
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+}
+
+// RestoreKillArchive loads an archive written by ArchiveKillsBefore back into kills,
+// recreating its partition, and rebuilds the rollups of the restored days, which were kept
+// when the partition was dropped. Bulk upserts do not write computed valuations, so restored
+// kills are valued again by the price job.
+func RestoreKillArchive(path string) (int, error) {
+	var first, last time.Time
+	total, err := restoreKills(path, func(kill *models.Kill) {
+		if first.IsZero() || kill.KillTime.Before(first) {
+			first = kill.KillTime
+		}
+		if kill.KillTime.After(last) {
+			last = kill.KillTime
+		}
+	})
+	if total == 0 {
+		return total, err
+	}
+
+	// Rebuild even after a failure, as the batches written so far were added to the rollups.
+	if rebuildErr := db.RebuildRollups(first, last); rebuildErr != nil {
+		log.Printf("Error rebuilding rollups of restored kills: %v", rebuildErr)
+		if err == nil {
+			err = rebuildErr
+		}
+	}
+	return total, err
+}
+
+// restoreKills writes the kills of an archive in batches, passing each to seen.
+func restoreKills(path string, seen func(*models.Kill)) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxArchiveLineBytes)
+
+	total := 0
+	batch := make([]models.Kill, 0, restoreBatchSize)
+	flush := func() error {
+		if err := db.RestoreKills(batch); err != nil {
+			return err
+		}
+		for i := range batch {
+			seen(&batch[i])
+		}
+		total += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		var kill models.Kill
+		if err := json.Unmarshal(scanner.Bytes(), &kill); err != nil {
+			return total, fmt.Errorf("error decoding line %d: %v", total+len(batch)+1, err)
+		}
+		batch = append(batch, kill)
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return total, err
+	}
+	return total, flush()
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "eve-ran-jobs")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("SQLITE_PATH", filepath.Join(dir, "test.db"))
+	db.InitDB()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func writeArchive(t *testing.T, path string, kills []models.Kill) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	for i := range kills {
+		if err := encoder.Encode(&kills[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreKillArchiveKeepsRollupCounts(t *testing.T) {
+	killTime := time.Date(2023, 2, 14, 9, 0, 0, 0, time.UTC)
+	kills := []models.Kill{
+		{KillmailID: 9001, CharacterID: 41, KillTime: killTime, SolarSystemID: 30000400, TotalValue: 10},
+		{KillmailID: 9002, CharacterID: 41, KillTime: killTime.Add(time.Hour), SolarSystemID: 30000400, TotalValue: 20},
+	}
+	if err := db.BulkUpsertKills(kills); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropping a partition removes its kills and keeps their rollups.
+	path := filepath.Join(t.TempDir(), "kills_y2023m02.ndjson.gz")
+	writeArchive(t, path, kills)
+	if err := db.DB.Where("killmail_id IN ?", []int64{9001, 9002}).Delete(&models.Kill{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := RestoreKillArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("restored %d kills, want 2", count)
+	}
+
+	var killCount int
+	err = db.DB.Table("daily_system_stats").Select("COALESCE(SUM(kill_count), 0)").
+		Where("solar_system_id = ?", 30000400).Scan(&killCount).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if killCount != 2 {
+		t.Errorf("daily_system_stats counts %d kills after the restore, want 2", killCount)
+	}
+}
+
+func TestLinkArchiveKeepsExistingArchives(t *testing.T) {
+	dir := t.TempDir()
+	for i, want := range []string{"kills_y2023m03.ndjson.gz", "kills_y2023m03.2.ndjson.gz", "kills_y2023m03.3.ndjson.gz"} {
+		tmp := filepath.Join(dir, "archive.tmp")
+		if err := os.WriteFile(tmp, []byte(fmt.Sprint(i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		path, err := linkArchive(tmp, dir, "kills_y2023m03")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(path) != want {
+			t.Errorf("archive %d written to %s, want %s", i, filepath.Base(path), want)
+		}
+		os.Remove(tmp)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "kills_y2023m03.ndjson.gz"))
+	if err != nil || string(data) != "0" {
+		t.Errorf("first archive holds %q, %v; want it unchanged", data, err)
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/services"
+	"github.com/tadeasf/eve-ran/src/valuation"
+)
+
+// defaultHistoryRegionID is The Forge, home of Jita.
+const defaultHistoryRegionID = 10000002
+
+// StartPriceFetcherJob fetches prices daily and values pending kills every 15 minutes. Valuation
+// reads prices with Postgres-only queries, so the job does not run on other backends.
+func StartPriceFetcherJob() {
+	if !db.IsPostgres() {
+		log.Println("Price fetching and kill valuation need Postgres, not starting them")
+		return
+	}
+
+	c := cron.New()
+	c.AddFunc("@daily", func() {
+		FetchAndStorePrices()
+		valuePendingKills()
+	})
+	c.AddFunc("@every 15m", valuePendingKills)
+	c.Start()
+
+	go func() {
+		FetchAndStorePrices()
+		valuePendingKills()
+	}()
+}
+
+func valuePendingKills() {
+	count, err := valuation.ValuePendingKills()
+	if err != nil {
+		log.Printf("Error valuing kills: %v", err)
+	}
+	if count > 0 {
+		log.Printf("Valued %d kills", count)
+	}
+}
+
+// historyRegionID reads MARKET_HISTORY_REGION_ID; 0 disables regional history ingestion.
+func historyRegionID() int {
+	value := os.Getenv("MARKET_HISTORY_REGION_ID")
+	if value == "" {
+		return defaultHistoryRegionID
+	}
+	regionID, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid MARKET_HISTORY_REGION_ID %q, using %d", value, defaultHistoryRegionID)
+		return defaultHistoryRegionID
+	}
+	return regionID
+}
+
+// FetchAndStorePrices stores today's ESI global prices and the regional order history
+// of every type seen on a victim.
+func FetchAndStorePrices() {
+	log.Println("Fetching market prices")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	marketPrices, err := services.FetchMarketPrices()
+	if err != nil {
+		log.Printf("Error fetching market prices: %v", err)
+	} else {
+		prices := make([]models.TypePrice, 0, len(marketPrices))
+		for _, p := range marketPrices {
+			prices = append(prices, models.TypePrice{
+				TypeID:        p.TypeID,
+				Date:          today,
+				AdjustedPrice: p.AdjustedPrice,
+				AveragePrice:  p.AveragePrice,
+			})
+		}
+		if err := db.UpsertTypePrices(prices); err != nil {
+			log.Printf("Error storing market prices: %v", err)
+		}
+		log.Printf("Stored %d market prices", len(prices))
+	}
+
+	regionID := historyRegionID()
+	if regionID == 0 {
+		return
+	}
+
+	typeIDs, err := db.GetKilledTypeIDs()
+	if err != nil {
+		log.Printf("Error loading killed type IDs: %v", err)
+		return
+	}
+
+	log.Printf("Fetching market history for %d types in region %d", len(typeIDs), regionID)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 10)
+	for _, typeID := range typeIDs {
+		wg.Add(1)
+		go func(typeID int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			fetchAndStoreMarketHistory(regionID, typeID)
+		}(typeID)
+	}
+	wg.Wait()
+
+	log.Println("Finished fetching market prices")
+}
+
+func fetchAndStoreMarketHistory(regionID, typeID int) {
+	history, err := services.FetchMarketHistory(regionID, typeID)
+	if err != nil {
+		log.Printf("Error fetching market history for type %d: %v", typeID, err)
+		return
+	}
+
+	prices := make([]models.TypePrice, 0, len(history))
+	for _, entry := range history {
+		date, err := time.Parse("2006-01-02", entry.Date)
+		if err != nil {
+			continue
+		}
+		prices = append(prices, models.TypePrice{
+			TypeID:       typeID,
+			RegionID:     regionID,
+			Date:         date,
+			AveragePrice: entry.Average,
+			Highest:      entry.Highest,
+			Lowest:       entry.Lowest,
+			Volume:       entry.Volume,
+			OrderCount:   entry.OrderCount,
+		})
+	}
+
+	if err := db.UpsertTypePrices(prices); err != nil {
+		log.Printf("Error storing market history for type %d: %v", typeID, err)
+	}
+}
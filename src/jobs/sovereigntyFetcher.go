@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/services"
+)
+
+// StartSovereigntyFetcherJob records sovereignty and faction warfare state hourly, matching
+// the ESI cache time of the sovereignty endpoints.
+func StartSovereigntyFetcherJob() {
+	c := cron.New()
+	c.AddFunc("@every 1h", FetchAndStoreSovereignty)
+	c.Start()
+
+	go FetchAndStoreSovereignty()
+}
+
+func FetchAndStoreSovereignty() {
+	log.Println("Starting sovereignty fetch")
+	now := time.Now().UTC()
+	fetchAndStoreSovereigntyMap(now)
+	fetchAndStoreSovereigntyStructures(now)
+	fetchAndStoreFactionWarfare(now)
+	log.Println("Finished sovereignty fetch")
+}
+
+func fetchAndStoreSovereigntyMap(at time.Time) {
+	systems, err := services.FetchSovereigntyMap()
+	if err != nil {
+		log.Printf("Error fetching sovereignty map: %v", err)
+		return
+	}
+
+	var snapshot []models.SovereigntyHistory
+	for _, system := range systems {
+		// The map lists every system, including those nobody holds
+		if system.AllianceID == 0 && system.CorporationID == 0 && system.FactionID == 0 {
+			continue
+		}
+		snapshot = append(snapshot, models.SovereigntyHistory{
+			SystemID:      system.SystemID,
+			AllianceID:    system.AllianceID,
+			CorporationID: system.CorporationID,
+			FactionID:     system.FactionID,
+		})
+	}
+
+	if err := db.RecordSovereignty(snapshot, at); err != nil {
+		log.Printf("Error storing sovereignty map: %v", err)
+	}
+}
+
+func fetchAndStoreSovereigntyStructures(at time.Time) {
+	structures, err := services.FetchSovereigntyStructures()
+	if err != nil {
+		log.Printf("Error fetching sovereignty structures: %v", err)
+		return
+	}
+
+	snapshot := make([]models.SovereigntyStructureHistory, 0, len(structures))
+	for _, structure := range structures {
+		snapshot = append(snapshot, models.SovereigntyStructureHistory{
+			StructureID:                 structure.StructureID,
+			StructureTypeID:             structure.StructureTypeID,
+			SystemID:                    structure.SolarSystemID,
+			AllianceID:                  structure.AllianceID,
+			VulnerabilityOccupancyLevel: structure.VulnerabilityOccupancyLevel,
+			VulnerableStartTime:         structure.VulnerableStartTime,
+			VulnerableEndTime:           structure.VulnerableEndTime,
+		})
+	}
+
+	if err := db.RecordSovereigntyStructures(snapshot, at); err != nil {
+		log.Printf("Error storing sovereignty structures: %v", err)
+	}
+}
+
+func fetchAndStoreFactionWarfare(at time.Time) {
+	systems, err := services.FetchFactionWarfareSystems()
+	if err != nil {
+		log.Printf("Error fetching faction warfare systems: %v", err)
+		return
+	}
+
+	snapshot := make([]models.FactionWarfareHistory, 0, len(systems))
+	for _, system := range systems {
+		snapshot = append(snapshot, models.FactionWarfareHistory{
+			SystemID:               system.SolarSystemID,
+			OwnerFactionID:         system.OwnerFactionID,
+			OccupierFactionID:      system.OccupierFactionID,
+			Contested:              system.Contested,
+			VictoryPoints:          system.VictoryPoints,
+			VictoryPointsThreshold: system.VictoryPointsThreshold,
+		})
+	}
+
+	if err := db.RecordFactionWarfare(snapshot, at); err != nil {
+		log.Printf("Error storing faction warfare systems: %v", err)
+	}
+}
@@ -17,6 +17,11 @@ import (
 
 const (
 	baseURL = "https://esi.evetech.net/latest"
+
+	// Universe rows are written in batches of universeBatchSize, or after universeBatchInterval
+	// when fetching slows down.
+	universeBatchSize     = 200
+	universeBatchInterval = 5 * time.Second
 )
 
 func FetchAndUpdateTypes() {
@@ -24,7 +29,11 @@ func FetchAndUpdateTypes() {
 	fetchAndUpdateRegions()
 	fetchAndUpdateConstellations()
 	fetchAndUpdateSystems()
+	fetchAndUpdateStargates()
 	fetchAndUpdateItems()
+	fetchAndUpdateItemGroups()
+	fetchAndUpdateLocalizedNames()
+	db.InvalidateUniverseCache()
 	log.Println("Finished FetchAndUpdateTypes job")
 }
 
@@ -36,11 +45,12 @@ func fetchAndUpdateRegions() {
 		return
 	}
 
-	for _, region := range regions {
-		err := db.UpsertRegion(region)
-		if err != nil {
-			log.Printf("Error upserting region %d: %v", region.RegionID, err)
-		}
+	rows := make([]models.Region, len(regions))
+	for i, region := range regions {
+		rows[i] = *region
+	}
+	if err := db.UpsertRegions(rows); err != nil {
+		log.Printf("Error upserting regions: %v", err)
 	}
 	log.Println("Finished fetching and updating regions")
 }
@@ -56,15 +66,19 @@ func fetchAndUpdateConstellations() {
 		existingMap[constellation.ConstellationID] = true
 	}
 
+	writer := db.NewBatchWriter(universeBatchSize, universeBatchInterval, db.UpsertConstellations)
 	for _, id := range ids {
 		if !existingMap[id] {
-			fetchAndSaveConstellation(id)
+			fetchAndSaveConstellation(id, writer)
 		}
 	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Error upserting constellations: %v", err)
+	}
 	log.Println("Finished fetching and updating constellations")
 }
 
-func fetchAndSaveConstellation(id int) {
+func fetchAndSaveConstellation(id int, writer *db.BatchWriter[models.Constellation]) {
 	url := baseURL + "/universe/constellations/" + strconv.Itoa(id) + "/"
 	resp, err := http.Get(url)
 	if err != nil {
@@ -77,9 +91,8 @@ func fetchAndSaveConstellation(id int) {
 	var constellation models.Constellation
 	json.Unmarshal(body, &constellation)
 
-	err = db.UpsertConstellation(&constellation)
-	if err != nil {
-		log.Printf("Error upserting constellation %d: %v", id, err)
+	if err := writer.Add(constellation); err != nil {
+		log.Printf("Error upserting constellations: %v", err)
 	}
 }
 
@@ -94,15 +107,23 @@ func fetchAndUpdateSystems() {
 		existingMap[system.SystemID] = true
 	}
 
+	writer := db.NewBatchWriter(universeBatchSize, universeBatchInterval, db.UpsertSystems)
 	for _, id := range ids {
 		if !existingMap[id] {
-			fetchAndSaveSystem(id)
+			fetchAndSaveSystem(id, writer)
 		}
 	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Error upserting systems: %v", err)
+	}
+
+	if err := db.ClassifySystems(); err != nil {
+		log.Printf("Error classifying systems: %v", err)
+	}
 	log.Println("Finished fetching and updating systems")
 }
 
-func fetchAndSaveSystem(id int) {
+func fetchAndSaveSystem(id int, writer *db.BatchWriter[models.System]) {
 	url := baseURL + "/universe/systems/" + strconv.Itoa(id) + "/"
 	resp, err := http.Get(url)
 	if err != nil {
@@ -115,10 +136,60 @@ func fetchAndSaveSystem(id int) {
 	var system models.System
 	json.Unmarshal(body, &system)
 
-	err = db.UpsertSystem(&system)
+	if err := writer.Add(system); err != nil {
+		log.Printf("Error upserting systems: %v", err)
+	}
+}
+
+// fetchAndUpdateStargates stores the stargates of every known system, giving the jump graph
+// used to cluster kills in neighbouring systems into battles.
+func fetchAndUpdateStargates() {
+	log.Println("Fetching and updating stargates")
+
+	systems, err := db.GetAllSystems()
 	if err != nil {
-		log.Printf("Error upserting system %d: %v", id, err)
+		log.Printf("Error loading systems: %v", err)
+		return
+	}
+
+	existingIDs, _ := db.GetAllStargateIDs()
+	existingMap := make(map[int]bool, len(existingIDs))
+	for _, id := range existingIDs {
+		existingMap[id] = true
+	}
+
+	writer := db.NewBatchWriter(universeBatchSize, universeBatchInterval, db.UpsertStargates)
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 20)
+	for _, system := range systems {
+		for _, id := range system.Stargates {
+			if existingMap[id] {
+				continue
+			}
+
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				stargate, err := services.FetchStargateInfo(id)
+				if err != nil {
+					log.Printf("Error fetching stargate %d: %v", id, err)
+					return
+				}
+				if err := writer.Add(*stargate); err != nil {
+					log.Printf("Error upserting stargates: %v", err)
+				}
+			}(id)
+		}
 	}
+	wg.Wait()
+	if err := writer.Close(); err != nil {
+		log.Printf("Error upserting stargates: %v", err)
+	}
+
+	log.Println("Finished fetching and updating stargates")
 }
 
 func fetchAndUpdateItems() {
@@ -131,6 +202,7 @@ func fetchAndUpdateItems() {
 		existingMap[item.TypeID] = true
 	}
 
+	writer := db.NewBatchWriter(universeBatchSize, universeBatchInterval, db.UpsertESIItems)
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, 20) // Limit to 20 concurrent requests
 	itemIDsChan := make(chan int, 100)
@@ -142,7 +214,7 @@ func fetchAndUpdateItems() {
 			defer wg.Done()
 			for id := range itemIDsChan {
 				semaphore <- struct{}{}
-				fetchAndSaveItem(id)
+				fetchAndSaveItem(id, writer)
 				<-semaphore
 			}
 		}()
@@ -172,10 +244,51 @@ func fetchAndUpdateItems() {
 
 	close(itemIDsChan)
 	wg.Wait()
+	if err := writer.Close(); err != nil {
+		log.Printf("Error upserting items: %v", err)
+	}
 
 	log.Println("Finished fetching and updating items")
 }
 
+// fetchAndUpdateItemGroups stores the groups of all known items, used to aggregate ships by class.
+func fetchAndUpdateItemGroups() {
+	log.Println("Fetching and updating item groups")
+
+	ids, err := db.GetMissingItemGroupIDs()
+	if err != nil {
+		log.Printf("Error loading missing item groups: %v", err)
+		return
+	}
+
+	writer := db.NewBatchWriter(universeBatchSize, universeBatchInterval, db.UpsertItemGroups)
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 20)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			group, err := services.FetchItemGroupInfo(id)
+			if err != nil {
+				log.Printf("Error fetching item group %d: %v", id, err)
+				return
+			}
+			if err := writer.Add(*group); err != nil {
+				log.Printf("Error upserting item groups: %v", err)
+			}
+		}(id)
+	}
+	wg.Wait()
+	if err := writer.Close(); err != nil {
+		log.Printf("Error upserting item groups: %v", err)
+	}
+
+	log.Println("Finished fetching and updating item groups")
+}
+
 func fetchItemIDsWithPagination(baseURL string, page int) ([]int, error) {
 	url := fmt.Sprintf("%s?datasource=tranquility&page=%d", baseURL, page)
 	client := &http.Client{}
@@ -210,7 +323,7 @@ func fetchItemIDsWithPagination(baseURL string, page int) ([]int, error) {
 	return ids, nil
 }
 
-func fetchAndSaveItem(id int) {
+func fetchAndSaveItem(id int, writer *db.BatchWriter[models.ESIItem]) {
 	if id == 0 {
 		log.Printf("Skipping item with ID 0")
 		return
@@ -241,9 +354,8 @@ func fetchAndSaveItem(id int) {
 		return
 	}
 
-	err = db.UpsertESIItem(&item)
-	if err != nil {
-		log.Printf("Error upserting item %d: %v", id, err)
+	if err := writer.Add(item); err != nil {
+		log.Printf("Error upserting items: %v", err)
 	}
 }
 
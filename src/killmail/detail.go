@@ -0,0 +1,186 @@
+// Package killmail assembles fully resolved killmail views from stored kills.
+package killmail
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/fitting"
+	"github.com/tadeasf/eve-ran/src/names"
+	"gorm.io/gorm"
+)
+
+// Entity is an ID paired with its resolved name. Name is empty when it could not be resolved.
+type Entity struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Location describes where a kill happened.
+type Location struct {
+	System         *Entity `json:"system"`
+	SecurityStatus float64 `json:"security_status"`
+	SecurityClass  string  `json:"security_class,omitempty"`
+	Constellation  *Entity `json:"constellation,omitempty"`
+	Region         *Entity `json:"region,omitempty"`
+}
+
+// Participant is the victim or one attacker with every ID resolved.
+type Participant struct {
+	Character      *Entity `json:"character,omitempty"`
+	Corporation    *Entity `json:"corporation,omitempty"`
+	Alliance       *Entity `json:"alliance,omitempty"`
+	Faction        *Entity `json:"faction,omitempty"`
+	Ship           *Entity `json:"ship,omitempty"`
+	Weapon         *Entity `json:"weapon,omitempty"`
+	DamageDone     int     `json:"damage_done,omitempty"`
+	DamageTaken    int     `json:"damage_taken,omitempty"`
+	FinalBlow      bool    `json:"final_blow,omitempty"`
+	SecurityStatus float64 `json:"security_status,omitempty"`
+}
+
+type Links struct {
+	ZKillboard string `json:"zkillboard"`
+	ESI        string `json:"esi"`
+}
+
+// Detail is a single kill with names resolved for its location, participants, ships and items.
+type Detail struct {
+	Kill      *models.Kill    `json:"kill"`
+	Location  Location        `json:"location"`
+	Victim    Participant     `json:"victim"`
+	Attackers []Participant   `json:"attackers"`
+	Items     fitting.Fitting `json:"items"`
+	Links     Links           `json:"links"`
+}
+
+// BuildDetail resolves every reference in the kill. Missing universe data or a failed name
+// lookup leaves the matching names empty rather than failing the whole view.
+func BuildDetail(kill *models.Kill) (*Detail, error) {
+	types, err := db.GetTypeInfos(typeIDs(kill))
+	if err != nil {
+		return nil, err
+	}
+
+	entityNames, err := names.Resolve(entityIDs(kill))
+	if err != nil {
+		log.Printf("Error resolving names for kill %d: %v", kill.KillmailID, err)
+	}
+
+	location, err := resolveLocation(kill.SolarSystemID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := resolver{types: types, names: entityNames}
+
+	detail := &Detail{
+		Kill:     kill,
+		Location: location,
+		Victim: Participant{
+			Character:   r.entity(kill.Victim.CharacterID),
+			Corporation: r.entity(kill.Victim.CorporationID),
+			Alliance:    r.entity(kill.Victim.AllianceID),
+			Faction:     r.entity(kill.Victim.FactionID),
+			Ship:        r.itemType(kill.Victim.ShipTypeID),
+			DamageTaken: kill.Victim.DamageTaken,
+		},
+		Attackers: make([]Participant, 0, len(kill.Attackers)),
+		Items:     fitting.Decode(kill, types),
+		Links: Links{
+			ZKillboard: fmt.Sprintf("https://zkillboard.com/kill/%d/", kill.KillmailID),
+			ESI:        fmt.Sprintf("https://esi.evetech.net/latest/killmails/%d/%s/", kill.KillmailID, kill.Hash),
+		},
+	}
+
+	for _, attacker := range kill.Attackers {
+		detail.Attackers = append(detail.Attackers, Participant{
+			Character:      r.entity(attacker.CharacterID),
+			Corporation:    r.entity(attacker.CorporationID),
+			Alliance:       r.entity(attacker.AllianceID),
+			Faction:        r.entity(attacker.FactionID),
+			Ship:           r.itemType(attacker.ShipTypeID),
+			Weapon:         r.itemType(attacker.WeaponTypeID),
+			DamageDone:     attacker.DamageDone,
+			FinalBlow:      attacker.FinalBlow,
+			SecurityStatus: attacker.SecurityStatus,
+		})
+	}
+
+	return detail, nil
+}
+
+func resolveLocation(systemID int) (Location, error) {
+	location := Location{System: &Entity{ID: int64(systemID)}}
+
+	system, err := db.GetSystemLocation(systemID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return location, nil
+	}
+	if err != nil {
+		return location, err
+	}
+	location.System.Name = system.Name
+	location.SecurityStatus = system.SecurityStatus
+	location.SecurityClass = system.SecurityClass
+
+	if system.ConstellationName != "" {
+		location.Constellation = &Entity{ID: int64(system.ConstellationID), Name: system.ConstellationName}
+	}
+	if system.RegionName != "" {
+		location.Region = &Entity{ID: int64(system.RegionID), Name: system.RegionName}
+	}
+
+	return location, nil
+}
+
+func typeIDs(kill *models.Kill) []int {
+	ids := fitting.TypeIDs(kill)
+	for _, attacker := range kill.Attackers {
+		ids = append(ids, attacker.ShipTypeID, attacker.WeaponTypeID)
+	}
+	return ids
+}
+
+func entityIDs(kill *models.Kill) []int64 {
+	var ids []int64
+	add := func(id *int) {
+		if id != nil {
+			ids = append(ids, int64(*id))
+		}
+	}
+
+	add(kill.Victim.CharacterID)
+	add(kill.Victim.CorporationID)
+	add(kill.Victim.AllianceID)
+	add(kill.Victim.FactionID)
+	for _, attacker := range kill.Attackers {
+		add(attacker.CharacterID)
+		add(attacker.CorporationID)
+		add(attacker.AllianceID)
+		add(attacker.FactionID)
+	}
+	return ids
+}
+
+type resolver struct {
+	types map[int]db.TypeInfo
+	names map[int64]string
+}
+
+func (r resolver) entity(id *int) *Entity {
+	if id == nil {
+		return nil
+	}
+	return &Entity{ID: int64(*id), Name: r.names[int64(*id)]}
+}
+
+func (r resolver) itemType(typeID int) *Entity {
+	if typeID == 0 {
+		return nil
+	}
+	return &Entity{ID: int64(typeID), Name: r.types[typeID].Name}
+}
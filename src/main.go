@@ -1,13 +1,21 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	_ "github.com/tadeasf/eve-ran/docs"
+	"github.com/tadeasf/eve-ran/src/battles"
+	"github.com/tadeasf/eve-ran/src/cache"
 	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
 	"github.com/tadeasf/eve-ran/src/jobs"
+	"github.com/tadeasf/eve-ran/src/notifications"
 	"github.com/tadeasf/eve-ran/src/routes"
+	"github.com/tadeasf/eve-ran/src/stream"
 )
 
 // @title EVE Ran API
@@ -18,14 +26,48 @@ import (
 // @schemes http https
 
 func main() {
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	db.InitDB()
 
+	// Deliver webhook notifications for newly ingested kills
+	notifications.Start()
+
+	// Broadcast newly ingested kills to live stream subscribers
+	stream.Start()
+
+	// Cache aggregate responses until new kills are ingested
+	cache.Start()
+
 	// Start the kill fetcher job
 	go jobs.StartKillFetcherJob()
 
 	// Run the type fetcher job
 	go jobs.FetchAndUpdateTypes()
 
+	// Record sovereignty and faction warfare history
+	jobs.StartSovereigntyFetcherJob()
+
+	// Battles, valuation and partitions rely on Postgres features
+	if db.IsPostgres() {
+		// Cluster newly ingested kills into battles
+		battles.Start()
+
+		// Ingest market prices and value kills
+		jobs.StartPriceFetcherJob()
+
+		// Create upcoming kill partitions and archive expired ones
+		jobs.StartPartitionMaintenanceJob()
+	}
+
+	// Build the daily rollups of a database that predates them
+	go func() {
+		if err := db.EnsureRollups(); err != nil {
+			log.Printf("Error building daily rollups: %v", err)
+		}
+	}()
 	r := gin.Default()
 
 	// zKillboard routes
@@ -57,14 +99,61 @@ func main() {
 
 	// New routes
 	r.GET("/characters/:id/killmails", routes.GetCharacterKillmails)
-	r.GET("/characters/stats", routes.GetAllCharacterStats)
+	r.GET("/characters/stats", cache.Responses(), routes.GetAllCharacterStats)
+	r.GET("/characters/associates/graph", cache.Responses(), routes.GetAssociateGraph)
+	r.GET("/characters/:id/associates", routes.GetCharacterAssociates)
+	r.GET("/characters/:id/ships", cache.Responses(), routes.GetCharacterShips)
+	r.GET("/characters/:id/stats", cache.Responses(), routes.GetCharacterStats)
 
 	// New data routes
 	r.GET("/characters", routes.GetAllCharacters)
 	r.GET("/kills", routes.GetAllKills)
 
 	// Add this line to register the GetKillsByRegion route
-	r.GET("/kills/region/:regionID", routes.GetKillsByRegion)
+	r.GET("/kills/region/:regionID", cache.Responses(), routes.GetKillsByRegion)
+	r.GET("/kills/timeseries", cache.Responses(), routes.GetKillTimeseries)
+	r.GET("/kills/:killmailID", routes.GetKill)
+	r.GET("/kills/:killmailID/fit", routes.GetKillFit)
+
+	// Price routes
+	r.POST("/prices/fetch", routes.FetchAndStorePrices)
+	r.GET("/prices/:typeID", routes.GetTypePriceHistory)
+	r.POST("/kills/revalue", routes.RevalueKills)
+
+	// Corporation routes
+	r.POST("/corporations", routes.AddTrackedEntity(models.EntityTypeCorporation))
+	r.GET("/corporations", routes.GetTrackedEntities(models.EntityTypeCorporation))
+	r.GET("/corporations/stats", cache.Responses(), routes.GetEntityStats(models.EntityTypeCorporation))
+	r.DELETE("/corporations/:id", routes.RemoveTrackedEntity(models.EntityTypeCorporation))
+	r.GET("/corporations/:id/kills", routes.GetEntityKills(models.EntityTypeCorporation))
+	r.GET("/corporations/:id/doctrines", cache.Responses(), routes.GetEntityDoctrines(models.EntityTypeCorporation))
+
+	// Alliance routes
+	r.POST("/alliances", routes.AddTrackedEntity(models.EntityTypeAlliance))
+	r.GET("/alliances", routes.GetTrackedEntities(models.EntityTypeAlliance))
+	r.GET("/alliances/stats", cache.Responses(), routes.GetEntityStats(models.EntityTypeAlliance))
+	r.DELETE("/alliances/:id", routes.RemoveTrackedEntity(models.EntityTypeAlliance))
+	r.GET("/alliances/:id/kills", routes.GetEntityKills(models.EntityTypeAlliance))
+	r.GET("/alliances/:id/doctrines", cache.Responses(), routes.GetEntityDoctrines(models.EntityTypeAlliance))
+
+	// Search routes
+	r.GET("/search", routes.Search)
+
+	// Battle routes
+	r.GET("/battles", routes.GetBattles)
+	r.GET("/battles/:id", routes.GetBattle)
+
+	// Live kill feed routes
+	r.GET("/stream/kills", routes.StreamKills)
+	r.GET("/stream/kills/ws", routes.StreamKillsWebSocket)
+
+	// Notification routes
+	r.GET("/notifications/rules", routes.GetNotificationRules)
+	r.POST("/notifications/rules", routes.CreateNotificationRule)
+	r.GET("/notifications/rules/:id", routes.GetNotificationRule)
+	r.PUT("/notifications/rules/:id", routes.UpdateNotificationRule)
+	r.DELETE("/notifications/rules/:id", routes.DeleteNotificationRule)
+	r.POST("/notifications/rules/:id/test", routes.TestNotificationRule)
 
 	// Setup Swagger
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
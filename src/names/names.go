@@ -0,0 +1,124 @@
+// Package names resolves character, corporation, alliance and faction IDs to names,
+// caching ESI lookups in the entity_names table.
+package names
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/services"
+)
+
+const (
+	esiBatchSize = 1000
+
+	// categoryUnknown marks an ID ESI rejected, cached so that views do not look it up again
+	// until unknownRetryAfter has passed.
+	categoryUnknown   = "unknown"
+	unknownRetryAfter = 24 * time.Hour
+)
+
+// fetchESINames looks names up on ESI; tests replace it.
+var fetchESINames = services.FetchNames
+
+// Resolve returns the names of the given IDs. IDs ESI does not know are left out of the result.
+// When a lookup fails, the names resolved so far are returned along with the error, so callers
+// can still show what is known.
+func Resolve(ids []int64) (map[int64]string, error) {
+	ids = unique(ids)
+	resolved := make(map[int64]string, len(ids))
+
+	cached, err := db.GetEntityNames(ids)
+	if err != nil {
+		return resolved, err
+	}
+
+	var missing []int64
+	for _, id := range ids {
+		name, ok := cached[id]
+		switch {
+		case !ok:
+			missing = append(missing, id)
+		case name.Category != categoryUnknown:
+			resolved[id] = name.Name
+		case time.Since(name.UpdatedAt) > unknownRetryAfter:
+			missing = append(missing, id)
+		}
+	}
+
+	for start := 0; start < len(missing); start += esiBatchSize {
+		end := start + esiBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		fetched, err := fetchNames(missing[start:end])
+		if err != nil {
+			return resolved, err
+		}
+		if err := db.UpsertEntityNames(fetched); err != nil {
+			return resolved, err
+		}
+		for _, name := range fetched {
+			if name.Category != categoryUnknown {
+				resolved[name.ID] = name.Name
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// fetchNames looks the batch up on ESI. Since a single invalid ID fails the whole request,
+// a batch rejected for unknown IDs is split in half until the offending IDs are isolated, which
+// are returned with categoryUnknown. Other failures, such as network errors and server errors,
+// are returned right away.
+func fetchNames(ids []int64) ([]models.EntityName, error) {
+	esiNames, err := fetchESINames(ids)
+	if errors.Is(err, services.ErrUnknownIDs) {
+		if len(ids) == 1 {
+			log.Printf("Error resolving name for ID %d: %v", ids[0], err)
+			return []models.EntityName{{ID: ids[0], Category: categoryUnknown, UpdatedAt: time.Now()}}, nil
+		}
+		mid := len(ids) / 2
+		first, err := fetchNames(ids[:mid])
+		if err != nil {
+			return nil, err
+		}
+		second, err := fetchNames(ids[mid:])
+		if err != nil {
+			return nil, err
+		}
+		return append(first, second...), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	names := make([]models.EntityName, 0, len(esiNames))
+	for _, name := range esiNames {
+		names = append(names, models.EntityName{
+			ID:        name.ID,
+			Category:  name.Category,
+			Name:      name.Name,
+			UpdatedAt: now,
+		})
+	}
+	return names, nil
+}
+
+func unique(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	var out []int64
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
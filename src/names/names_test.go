@@ -0,0 +1,88 @@
+package names
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/services"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "eve-ran-names")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("SQLITE_PATH", filepath.Join(dir, "test.db"))
+	db.InitDB()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// stubESI answers name lookups like ESI, rejecting whole batches that contain an unknown ID,
+// and counts the requests.
+func stubESI(t *testing.T, unknown map[int64]bool) *int {
+	requests := 0
+	fetchESINames = func(ids []int64) ([]services.ESIName, error) {
+		requests++
+		var names []services.ESIName
+		for _, id := range ids {
+			if unknown[id] {
+				return nil, services.ErrUnknownIDs
+			}
+			names = append(names, services.ESIName{ID: id, Category: "character", Name: fmt.Sprint("pilot ", id)})
+		}
+		return names, nil
+	}
+	t.Cleanup(func() { fetchESINames = services.FetchNames })
+	return &requests
+}
+
+func TestResolveCachesUnknownIDs(t *testing.T) {
+	requests := stubESI(t, map[int64]bool{1003: true})
+
+	resolved, err := Resolve([]int64{1001, 1002, 1003})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 2 || resolved[1001] != "pilot 1001" || resolved[1002] != "pilot 1002" {
+		t.Errorf("Resolve = %v, want the two known IDs", resolved)
+	}
+
+	*requests = 0
+	resolved, err = Resolve([]int64{1001, 1003})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *requests != 0 {
+		t.Errorf("resolving cached and rejected IDs made %d ESI requests, want 0", *requests)
+	}
+	if _, ok := resolved[1003]; ok || resolved[1001] != "pilot 1001" {
+		t.Errorf("Resolve = %v, want only 1001", resolved)
+	}
+}
+
+func TestResolveReturnsCachedNamesOnFailure(t *testing.T) {
+	stubESI(t, nil)
+	if _, err := Resolve([]int64{2001}); err != nil {
+		t.Fatal(err)
+	}
+
+	down := errors.New("ESI unavailable")
+	fetchESINames = func([]int64) ([]services.ESIName, error) { return nil, down }
+
+	resolved, err := Resolve([]int64{2001, 2002})
+	if !errors.Is(err, down) {
+		t.Errorf("Resolve error = %v, want %v", err, down)
+	}
+	if resolved[2001] != "pilot 2001" {
+		t.Errorf("Resolve = %v, want the cached name of 2001", resolved)
+	}
+}
@@ -0,0 +1,170 @@
+// Package network builds the co-attacker graph of characters for export to graph tools such as Gephi.
+package network
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/names"
+)
+
+type Node struct {
+	ID      int64  `json:"id"`
+	Label   string `json:"label"`
+	Tracked bool   `json:"tracked"`
+	Kills   int    `json:"kills"`
+}
+
+type Edge struct {
+	Source   int64  `json:"source"`
+	Target   int64  `json:"target"`
+	Weight   int    `json:"weight"`
+	LastSeen string `json:"last_seen"`
+}
+
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Build returns the graph of tracked characters and those who attacked alongside them on at
+// least minKills kills matching the filter, keeping the limit strongest edges. A node's Kills
+// is the sum of its edge weights.
+func Build(filter db.KillFilter, minKills, limit int) (*Graph, error) {
+	coAttackers, err := db.GetCoAttackerEdges(filter, minKills, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	characters, err := db.GetAllCharacters()
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[int64]bool, len(characters))
+	for _, character := range characters {
+		tracked[character.ID] = true
+	}
+
+	graph := &Graph{Nodes: []Node{}, Edges: make([]Edge, 0, len(coAttackers))}
+	index := make(map[int64]int)
+	var ids []int64
+	node := func(id int64) *Node {
+		i, ok := index[id]
+		if !ok {
+			i = len(graph.Nodes)
+			index[id] = i
+			ids = append(ids, id)
+			graph.Nodes = append(graph.Nodes, Node{ID: id, Tracked: tracked[id]})
+		}
+		return &graph.Nodes[i]
+	}
+
+	for _, edge := range coAttackers {
+		node(edge.Source).Kills += edge.Kills
+		node(edge.Target).Kills += edge.Kills
+		graph.Edges = append(graph.Edges, Edge{
+			Source:   edge.Source,
+			Target:   edge.Target,
+			Weight:   edge.Kills,
+			LastSeen: edge.LastSeen.UTC().Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	characterNames, err := names.Resolve(ids)
+	if err != nil {
+		log.Printf("Error resolving names for the associate graph: %v", err)
+	}
+	for i := range graph.Nodes {
+		graph.Nodes[i].Label = characterNames[graph.Nodes[i].ID]
+	}
+
+	return graph, nil
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as undirected GraphML with label, tracked, kills, weight
+// and last_seen attributes.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "tracked", For: "node", AttrName: "tracked", AttrType: "boolean"},
+			{ID: "kills", For: "node", AttrName: "kills", AttrType: "int"},
+			{ID: "weight", For: "edge", AttrName: "weight", AttrType: "int"},
+			{ID: "last_seen", For: "edge", AttrName: "last_seen", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "associates", EdgeDefault: "undirected"},
+	}
+
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: strconv.FormatInt(node.ID, 10),
+			Data: []graphMLData{
+				{Key: "label", Value: node.Label},
+				{Key: "tracked", Value: strconv.FormatBool(node.Tracked)},
+				{Key: "kills", Value: strconv.Itoa(node.Kills)},
+			},
+		})
+	}
+	for _, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: strconv.FormatInt(edge.Source, 10),
+			Target: strconv.FormatInt(edge.Target, 10),
+			Data: []graphMLData{
+				{Key: "weight", Value: strconv.Itoa(edge.Weight)},
+				{Key: "last_seen", Value: edge.LastSeen},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
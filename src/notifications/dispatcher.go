@@ -0,0 +1,216 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+const (
+	queueSize       = 1000
+	deliveryWorkers = 4
+	maxAttempts     = 5
+	maxBackoff      = time.Minute
+)
+
+type delivery struct {
+	rule    models.NotificationRule
+	payload []byte
+}
+
+var (
+	killQueue     = make(chan *models.Kill, queueSize)
+	deliveryQueue = make(chan delivery, queueSize)
+	httpClient    = &http.Client{Timeout: 10 * time.Second}
+
+	// testClient sends test notifications, which the caller waits for, so it gives up quickly.
+	testClient = &http.Client{Timeout: 5 * time.Second}
+
+	baseBackoff = 2 * time.Second
+)
+
+// Start registers the dispatcher with the ingestion path and starts its workers.
+func Start() {
+	db.OnKillUpserted(func(kill *models.Kill) {
+		k := *kill
+		select {
+		case killQueue <- &k:
+		default:
+			log.Printf("Notification queue full, dropping kill %d", kill.KillmailID)
+		}
+	})
+
+	go func() {
+		for kill := range killQueue {
+			dispatchKill(kill)
+		}
+	}()
+
+	for i := 0; i < deliveryWorkers; i++ {
+		go func() {
+			for d := range deliveryQueue {
+				if err := deliver(d.rule, d.payload); err != nil {
+					log.Printf("Error delivering notification for rule %d: %v", d.rule.ID, err)
+				}
+			}
+		}()
+	}
+}
+
+func dispatchKill(kill *models.Kill) {
+	rules, err := db.GetEnabledNotificationRules()
+	if err != nil {
+		log.Printf("Error loading notification rules: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	details := resolveKillDetails(kill)
+	for _, rule := range rules {
+		if !matches(rule, kill, details) {
+			continue
+		}
+
+		payload, err := buildPayload(rule, kill, details)
+		if err != nil {
+			log.Printf("Error building notification for rule %d: %v", rule.ID, err)
+			continue
+		}
+
+		select {
+		case deliveryQueue <- delivery{rule: rule, payload: payload}:
+		default:
+			log.Printf("Delivery queue full, dropping notification for rule %d", rule.ID)
+		}
+	}
+}
+
+func matches(rule models.NotificationRule, kill *models.Kill, details killDetails) bool {
+	if kill.TotalValue < rule.MinTotalValue {
+		return false
+	}
+	if rule.SoloOnly && !kill.Solo {
+		return false
+	}
+	if len(rule.SystemIDs) > 0 && !contains(rule.SystemIDs, kill.SolarSystemID) {
+		return false
+	}
+	if len(rule.RegionIDs) > 0 && !contains(rule.RegionIDs, details.RegionID) {
+		return false
+	}
+	if len(rule.VictimShipGroupIDs) > 0 && !contains(rule.VictimShipGroupIDs, details.ShipGroupID) {
+		return false
+	}
+	if len(rule.CharacterIDs) > 0 && !involvesCharacter(rule.CharacterIDs, kill) {
+		return false
+	}
+	return true
+}
+
+func involvesCharacter(characterIDs models.IntArray, kill *models.Kill) bool {
+	for _, characterID := range characterIDs {
+		if kill.InvolvesCharacter(int64(characterID)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values models.IntArray, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SendTestNotification delivers the most recent stored kill to the rule's webhook,
+// bypassing the rule's criteria, so a webhook can be verified against a local sink. It makes
+// a single attempt, as the request waits on it.
+func SendTestNotification(rule models.NotificationRule) error {
+	kill, err := db.GetLatestKill()
+	if err != nil {
+		return err
+	}
+	if kill == nil {
+		return fmt.Errorf("no kills stored yet")
+	}
+
+	payload, err := buildPayload(rule, kill, resolveKillDetails(kill))
+	if err != nil {
+		return err
+	}
+	_, err = post(testClient, rule.WebhookURL, payload)
+	return err
+}
+
+// deliver posts the payload to the rule's webhook, retrying network errors, 429s and
+// server errors with exponential backoff. Retry-After is honored when present.
+func deliver(rule models.NotificationRule, payload []byte) error {
+	backoff := baseBackoff
+	var lastErr error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		wait, err := post(httpClient, rule.WebhookURL, payload)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if wait < 0 {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		log.Printf("Webhook delivery for rule %d failed: %v. Retrying in %v (attempt %d/%d)", rule.ID, err, wait, attempt, maxAttempts)
+		time.Sleep(wait)
+	}
+
+	return fmt.Errorf("failed to deliver webhook after %d attempts: %v", maxAttempts, lastErr)
+}
+
+// post sends a single webhook request. The returned duration is -1 when the failure
+// is permanent, the server-requested delay for 429s, and 0 otherwise.
+func post(client *http.Client, url string, payload []byte) (time.Duration, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EVE Ran Application - GitHub: tadeasf/eve-ran")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, fmt.Errorf("rate limited: %s", resp.Status)
+	case resp.StatusCode >= 500:
+		return 0, fmt.Errorf("server error: %s", resp.Status)
+	default:
+		return -1, fmt.Errorf("webhook rejected request: %s", resp.Status)
+	}
+}
@@ -0,0 +1,184 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "eve-ran-notifications")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("SQLITE_PATH", filepath.Join(dir, "test.db"))
+	db.InitDB()
+	Start()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// sink is a local webhook that answers with the given statuses in turn, then with 200.
+type sink struct {
+	*httptest.Server
+	requests atomic.Int32
+	bodies   chan []byte
+}
+
+func newSink(t *testing.T, statuses ...int) *sink {
+	s := &sink{bodies: make(chan []byte, 16)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(s.requests.Add(1))
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+		}
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		s.bodies <- body
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testKill(killmailID int64, value float64) models.Kill {
+	return models.Kill{
+		KillmailID:    killmailID,
+		KillTime:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		SolarSystemID: 30000142,
+		TotalValue:    value,
+		Victim:        models.Victim{ShipTypeID: 587},
+	}
+}
+
+func TestDispatchNotifiesNewKillsOnce(t *testing.T) {
+	s := newSink(t)
+	rule := models.NotificationRule{Name: "sink", WebhookURL: s.URL, Format: models.WebhookFormatGeneric, Enabled: true}
+	if err := db.CreateNotificationRule(&rule); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DeleteNotificationRule(rule.ID) })
+
+	if err := db.UpsertKills([]models.Kill{testKill(1001, 1e6)}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case body := <-s.bodies:
+		var payload struct {
+			RuleID uint        `json:"rule_id"`
+			Kill   models.Kill `json:"kill"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.RuleID != rule.ID || payload.Kill.KillmailID != 1001 {
+			t.Errorf("payload = rule %d kill %d, want rule %d kill 1001", payload.RuleID, payload.Kill.KillmailID, rule.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification delivered")
+	}
+
+	if err := db.UpsertKills([]models.Kill{testKill(1001, 2e6)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.bodies:
+		t.Fatal("re-upserted kill was notified again")
+	case <-time.After(500 * time.Millisecond):
+	}
+	if n := s.requests.Load(); n != 1 {
+		t.Errorf("sink received %d requests, want 1", n)
+	}
+}
+
+func TestDispatchSkipsKillsBelowMinimumValue(t *testing.T) {
+	s := newSink(t)
+	rule := models.NotificationRule{Name: "expensive", WebhookURL: s.URL, Enabled: true, MinTotalValue: 1e9}
+	if err := db.CreateNotificationRule(&rule); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DeleteNotificationRule(rule.ID) })
+
+	if err := db.UpsertKills([]models.Kill{testKill(1002, 1e6)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.bodies:
+		t.Fatal("kill below the rule's minimum value was notified")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestDeliverRetriesServerErrors(t *testing.T) {
+	defer func(backoff time.Duration) { baseBackoff = backoff }(baseBackoff)
+	baseBackoff = 10 * time.Millisecond
+
+	s := newSink(t, http.StatusInternalServerError, http.StatusBadGateway)
+	rule := models.NotificationRule{ID: 1, WebhookURL: s.URL}
+
+	if err := deliver(rule, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.requests.Load(); n != 3 {
+		t.Errorf("sink received %d requests, want 3", n)
+	}
+}
+
+func TestDeliverStopsOnRejection(t *testing.T) {
+	s := newSink(t, http.StatusBadRequest)
+	rule := models.NotificationRule{ID: 1, WebhookURL: s.URL}
+
+	if err := deliver(rule, []byte(`{}`)); err == nil {
+		t.Fatal("expected an error for a rejected webhook")
+	}
+	if n := s.requests.Load(); n != 1 {
+		t.Errorf("sink received %d requests, want 1", n)
+	}
+}
+
+func TestSendTestNotificationMakesOneAttempt(t *testing.T) {
+	kill := testKill(1003, 5e6)
+	if err := db.InsertKill(&kill); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newSink(t, http.StatusServiceUnavailable)
+	rule := models.NotificationRule{ID: 1, WebhookURL: s.URL}
+
+	start := time.Now()
+	if err := SendTestNotification(rule); err == nil {
+		t.Fatal("expected an error for a failing webhook")
+	}
+	if n := s.requests.Load(); n != 1 {
+		t.Errorf("sink received %d requests, want 1", n)
+	}
+	if elapsed := time.Since(start); elapsed > baseBackoff {
+		t.Errorf("test notification took %v, expected no retry", elapsed)
+	}
+
+	if err := SendTestNotification(rule); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.bodies:
+	default:
+		t.Fatal("test notification not delivered")
+	}
+}
@@ -0,0 +1,107 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// killDetails holds the universe lookups needed to match rules and render messages.
+type killDetails struct {
+	RegionID    int
+	SystemName  string
+	ShipGroupID int
+	ShipName    string
+}
+
+// object is a loosely typed JSON object used for the chat payload formats.
+type object = map[string]interface{}
+
+func resolveKillDetails(kill *models.Kill) killDetails {
+	details := killDetails{SystemName: fmt.Sprintf("System %d", kill.SolarSystemID)}
+
+	if system, err := db.GetSystemLocation(kill.SolarSystemID); err == nil {
+		details.SystemName = system.Name
+		details.RegionID = system.RegionID
+	}
+
+	details.ShipName = fmt.Sprintf("Type %d", kill.Victim.ShipTypeID)
+	if item, err := db.GetTypeInfo(kill.Victim.ShipTypeID); err == nil {
+		details.ShipName = item.Name
+		details.ShipGroupID = item.GroupID
+	}
+
+	return details
+}
+
+func zkillboardURL(killmailID int64) string {
+	return fmt.Sprintf("https://zkillboard.com/kill/%d/", killmailID)
+}
+
+func summary(kill *models.Kill, details killDetails) string {
+	return fmt.Sprintf("%s destroyed in %s (%s ISK)", details.ShipName, details.SystemName, formatISK(kill.TotalValue))
+}
+
+func formatISK(value float64) string {
+	switch {
+	case value >= 1e9:
+		return fmt.Sprintf("%.2fb", value/1e9)
+	case value >= 1e6:
+		return fmt.Sprintf("%.2fm", value/1e6)
+	case value >= 1e3:
+		return fmt.Sprintf("%.2fk", value/1e3)
+	default:
+		return fmt.Sprintf("%.0f", value)
+	}
+}
+
+func buildPayload(rule models.NotificationRule, kill *models.Kill, details killDetails) ([]byte, error) {
+	switch rule.Format {
+	case models.WebhookFormatDiscord:
+		return json.Marshal(discordPayload(rule, kill, details))
+	case models.WebhookFormatSlack:
+		return json.Marshal(slackPayload(rule, kill, details))
+	default:
+		return json.Marshal(object{
+			"rule_id":        rule.ID,
+			"rule_name":      rule.Name,
+			"summary":        summary(kill, details),
+			"zkillboard_url": zkillboardURL(kill.KillmailID),
+			"region_id":      details.RegionID,
+			"kill":           kill,
+		})
+	}
+}
+
+func discordPayload(rule models.NotificationRule, kill *models.Kill, details killDetails) object {
+	return object{
+		"username": "EVE Ran",
+		"embeds": []object{{
+			"title":       summary(kill, details),
+			"url":         zkillboardURL(kill.KillmailID),
+			"description": fmt.Sprintf("Matched rule **%s**", rule.Name),
+			"timestamp":   kill.KillTime.Format(time.RFC3339),
+			"fields": []object{
+				{"name": "Value", "value": formatISK(kill.TotalValue) + " ISK", "inline": true},
+				{"name": "Attackers", "value": fmt.Sprintf("%d", len(kill.Attackers)), "inline": true},
+				{"name": "Solo", "value": fmt.Sprintf("%t", kill.Solo), "inline": true},
+			},
+		}},
+	}
+}
+
+func slackPayload(rule models.NotificationRule, kill *models.Kill, details killDetails) object {
+	text := fmt.Sprintf("<%s|%s>", zkillboardURL(kill.KillmailID), summary(kill, details))
+	return object{
+		"text": text,
+		"blocks": []object{
+			{"type": "section", "text": object{"type": "mrkdwn", "text": text}},
+			{"type": "context", "elements": []object{
+				{"type": "mrkdwn", "text": fmt.Sprintf("Rule *%s* · %d attackers · %s", rule.Name, len(kill.Attackers), kill.KillTime.Format(time.RFC3339))},
+			}},
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/names"
+	"github.com/tadeasf/eve-ran/src/network"
+)
+
+// GetCharacterAssociates retrieves a character's most frequent co-attackers
+// @Summary Get character associates
+// @Description Fetch the characters, corporations and alliances most often seen attacking alongside a character, with kill counts and last-seen time
+// @Tags characters
+// @Produce json
+// @Param id path int true "Character ID"
+// @Param limit query int false "Maximum associates per entity type" default(20)
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} db.CharacterAssociates
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /characters/{id}/associates [get]
+func GetCharacterAssociates(c *gin.Context) {
+	characterID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid character ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: expected an integer between 1 and 100"})
+		return
+	}
+
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	result := db.CharacterAssociates{CharacterID: characterID}
+	groups := []struct {
+		entityType string
+		target     *[]db.Associate
+	}{
+		{models.EntityTypeCharacter, &result.Characters},
+		{models.EntityTypeCorporation, &result.Corporations},
+		{models.EntityTypeAlliance, &result.Alliances},
+	}
+
+	var ids []int64
+	for _, group := range groups {
+		associates, err := db.GetAssociates(characterID, group.entityType, filter, limit)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		for _, associate := range associates {
+			ids = append(ids, associate.ID)
+		}
+		*group.target = associates
+	}
+
+	entityNames, err := names.Resolve(ids)
+	if err != nil {
+		log.Printf("Error resolving names of associates of character %d: %v", characterID, err)
+	}
+	for _, group := range groups {
+		for i := range *group.target {
+			(*group.target)[i].Name = entityNames[(*group.target)[i].ID]
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAssociateGraph exports the co-attacker network
+// @Summary Export the associate graph
+// @Description Export tracked characters and the characters who attacked alongside them as a graph, with edges weighted by shared kills. GraphML output can be opened directly in Gephi.
+// @Tags characters
+// @Produce json,xml
+// @Param format query string false "Output format" Enums(json, graphml)
+// @Param minKills query int false "Minimum shared kills for an edge" default(2)
+// @Param limit query int false "Maximum number of edges, strongest first (max 5000)" default(500)
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} network.Graph
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /characters/associates/graph [get]
+func GetAssociateGraph(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "graphml" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format: expected one of json, graphml"})
+		return
+	}
+
+	minKills, err := strconv.Atoi(c.DefaultQuery("minKills", "2"))
+	if err != nil || minKills < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid minKills: expected a positive integer"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit < 1 || limit > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: expected an integer between 1 and 5000"})
+		return
+	}
+
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	graph, err := network.Build(filter, minKills, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, graph)
+		return
+	}
+
+	c.Header("Content-Type", "application/graphml+xml")
+	c.Header("Content-Disposition", `attachment; filename="associates.graphml"`)
+	c.Status(http.StatusOK)
+	if err := graph.WriteGraphML(c.Writer); err != nil {
+		log.Printf("Error writing associate graph as GraphML: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/battles"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// GetBattles lists detected battles
+// @Summary List battles
+// @Description Fetch battles, newest first. Kills within 15 minutes of each other in the same or adjacent systems are clustered into one battle. The shared kill filters select battles containing at least one matching kill.
+// @Tags battles
+// @Produce json
+// @Param minKills query int false "Minimum number of kills" default(2)
+// @Param characterID query []int false "Character IDs"
+// @Param corporationID query []int false "Corporation IDs"
+// @Param allianceID query []int false "Alliance IDs"
+// @Param systemID query []int false "Solar system IDs"
+// @Param regionID query []int false "Region IDs"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.Battle}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /battles [get]
+func GetBattles(c *gin.Context) {
+	minKills, err := strconv.Atoi(c.DefaultQuery("minKills", "2"))
+	if err != nil || minKills < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid minKills: expected a positive integer"})
+		return
+	}
+
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize, ok := bindPagination(c, 20)
+	if !ok {
+		return
+	}
+
+	battleList, totalCount, err := db.GetBattles(filter, minKills, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       battleList,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalItems: int(totalCount),
+		TotalPages: int(math.Ceil(float64(totalCount) / float64(pageSize))),
+	})
+}
+
+// GetBattle retrieves a battle report
+// @Summary Get a battle report
+// @Description Fetch a battle broken down into sides inferred from who shot whom, with per-side ISK lost, ships lost by type and participating pilots
+// @Tags battles
+// @Produce json
+// @Param id path int true "Battle ID"
+// @Success 200 {object} battles.Report
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /battles/{id} [get]
+func GetBattle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid battle ID"})
+		return
+	}
+
+	battle, err := db.GetBattle(uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if battle == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Battle not found"})
+		return
+	}
+
+	report, err := battles.BuildReport(battle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -3,10 +3,11 @@ package routes
 import (
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/export"
 )
 
 // GetAllCharacters retrieves all characters from the database
@@ -27,22 +28,97 @@ func GetAllCharacters(c *gin.Context) {
 	c.JSON(http.StatusOK, characters)
 }
 
-// GetAllKills retrieves all kills from the database
-// @Summary Get all kills
-// @Description Fetch all kills from the database
+// GetAllKills retrieves kills from the database
+// @Summary Get kills
+// @Description Fetch kills using cursor pagination on (sort key, killmail_id). Pass next_cursor or prev_cursor
+// @Description from a previous response as cursor to move between pages; a cursor carries its own sort order.
 // @Tags kills
 // @Accept json
-// @Produce json
-// @Success 200 {array} models.Kill
+// @Produce json,text/csv,application/x-ndjson,application/vnd.apache.parquet
+// @Param characterID query []int false "Tracked character IDs"
+// @Param corporationID query []int false "Corporation IDs"
+// @Param allianceID query []int false "Alliance IDs"
+// @Param systemID query []int false "Solar system IDs"
+// @Param constellationID query []int false "Constellation IDs"
+// @Param regionID query []int false "Region IDs"
+// @Param shipTypeID query []int false "Victim ship type IDs"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param minValue query number false "Minimum total value"
+// @Param maxValue query number false "Maximum total value"
+// @Param solo query bool false "Solo kills only (true) or excluded (false)"
+// @Param npc query bool false "NPC kills only (true) or excluded (false)"
+// @Param awox query bool false "Awox kills only (true) or excluded (false)"
+// @Param sort query string false "Sort order" Enums(time_desc, time_asc, value_desc, value_asc)
+// @Param cursor query string false "Page cursor"
+// @Param pageSize query int false "Page size (max 500)"
+// @Param includeTotal query bool false "Include the total number of matching kills"
+// @Param format query string false "Response format, overrides the Accept header" Enums(json, csv, ndjson, parquet)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.Kill}
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /kills [get]
 func GetAllKills(c *gin.Context) {
-	kills, err := db.GetAllKills()
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		exportKills(c, format, filter, "kills")
+		return
+	}
+
+	sortName := c.DefaultQuery("sort", "time_desc")
+	if _, ok := db.KillSorts[sortName]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort: expected one of time_desc, time_asc, value_desc, value_asc"})
+		return
+	}
+
+	var cursor *db.KillCursor
+	var err error
+	if encoded := c.Query("cursor"); encoded != "" {
+		cursor, err = db.DecodeKillCursor(encoded)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor: " + err.Error()})
+			return
+		}
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+	if err != nil || pageSize < 1 || pageSize > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pageSize: expected an integer between 1 and 500"})
+		return
+	}
+
+	kills, next, prev, err := db.ListKills(filter, sortName, cursor, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, kills)
+
+	response := models.PaginatedResponse{
+		Data:       kills,
+		PageSize:   pageSize,
+		NextCursor: next,
+		PrevCursor: prev,
+	}
+
+	if c.Query("includeTotal") == "true" {
+		total, err := db.CountKills(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response.TotalItems = int(total)
+		response.TotalPages = int((total + int64(pageSize) - 1) / int64(pageSize))
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetAllCharacterStats retrieves stats for all characters with filters
@@ -50,52 +126,78 @@ func GetAllKills(c *gin.Context) {
 // @Description Fetch stats for all characters from the database with optional filters
 // @Tags characters
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/vnd.apache.parquet
 // @Param regionID query []int false "Region IDs"
-// @Param startDate query string false "Start date (YYYY-MM-DD)"
-// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param systemID query []int false "Solar system IDs"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param space query []string false "Space classes (highsec, lowsec, nullsec, pochven, c1-c6, thera, shattered, drifter, abyssal) or groups (kspace, wormhole)"
+// @Param breakdown query string false "Split rows by space class" Enums(space)
+// @Param format query string false "Response format, overrides the Accept header" Enums(json, csv, ndjson, parquet)
 // @Success 200 {array} db.CharacterStats
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /characters/stats [get]
 func GetAllCharacterStats(c *gin.Context) {
-	regionIDs := c.QueryArray("regionID")
-	startDate := c.Query("startDate")
-	endDate := c.Query("endDate")
-
-	// Convert regionIDs from string to int
-	var regionIDInts []int64
-	for _, id := range regionIDs {
-		intID, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid region ID"})
-			return
-		}
-		regionIDInts = append(regionIDInts, intID)
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
 	}
 
-	// Parse dates
-	var startTime, endTime time.Time
-	var err error
-	if startDate != "" {
-		startTime, err = time.Parse("2006-01-02", startDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
-			return
-		}
+	bySpace, ok := bindSpaceBreakdown(c)
+	if !ok {
+		return
 	}
-	if endDate != "" {
-		endTime, err = time.Parse("2006-01-02", endDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
-			return
-		}
+
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		exportCharacterStats(c, format, filter, bySpace)
+		return
 	}
 
-	stats, err := db.GetCharacterStats(startTime, endTime, 0, regionIDInts...)
+	stats, err := db.GetCharacterStats(filter, bySpace)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetCharacterStats retrieves extended stats for one character
+// @Summary Get character stats
+// @Description Fetch extended stats over the kills a character attacked on: solo, fleet, NPC and awox kills, final blows, average fleet size and damage share, top system and region, biggest kill, zKillboard points, active days and a breakdown by space class
+// @Tags characters
+// @Produce json
+// @Param id path int true "Character ID"
+// @Param space query []string false "Space classes (highsec, lowsec, nullsec, pochven, c1-c6, thera, shattered, drifter, abyssal) or groups (kspace, wormhole)"
+// @Param regionID query []int false "Region IDs"
+// @Param systemID query []int false "Solar system IDs"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} db.CharacterDetailStats
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /characters/{id}/stats [get]
+func GetCharacterStats(c *gin.Context) {
+	characterID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid character ID"})
+		return
+	}
+
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := db.GetCharacterDetailStats(characterID, filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
 	"github.com/tadeasf/eve-ran/src/services"
 )
 
@@ -16,25 +17,55 @@ func FetchAndStoreConstellations(c *gin.Context) {
 		return
 	}
 
-	for _, constellation := range constellations {
-		err = db.UpsertConstellation(constellation)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	err = db.UpsertConstellations(derefAll(constellations))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
+	db.InvalidateUniverseCache()
+
 	c.JSON(http.StatusOK, gin.H{"message": "Constellations fetched and stored successfully", "count": len(constellations)})
 }
 
+// GetAllConstellations lists constellations
+// @Summary List constellations
+// @Description Fetch constellations, optionally filtered, projected and paginated. Responses carry an ETag and honour If-None-Match.
+// @Tags constellations
+// @Produce json
+// @Param regionID query []int false "Region IDs"
+// @Param lang query string false "Response language; defaults to Accept-Language, then en"
+// @Param fields query string false "Comma-separated fields to return"
+// @Param page query int false "Page number; paginates the response when set"
+// @Param pageSize query int false "Page size; paginates the response when set"
+// @Success 200 {array} models.Constellation
+// @Success 304
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /constellations [get]
 func GetAllConstellations(c *gin.Context) {
-	constellations, err := db.GetAllConstellations()
+	filter, ok := bindUniverseFilter(c, db.ParseConstellationFilter)
+	if !ok {
+		return
+	}
+	listConstellations(c, filter)
+}
+
+func listConstellations(c *gin.Context, filter db.ConstellationFilter) {
+	q, ok := bindUniverseQuery[models.Constellation](c)
+	if !ok {
+		return
+	}
+
+	constellations, total, err := db.ListConstellations(filter, q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	c.JSON(http.StatusOK, constellations)
+	if !localizeConstellations(c, bindLanguage(c), constellations) {
+		return
+	}
+	respondUniverse(c, constellations, total, q)
 }
 
 func GetConstellationByID(c *gin.Context) {
@@ -55,6 +86,12 @@ func GetConstellationByID(c *gin.Context) {
 		return
 	}
 
+	constellations := []models.Constellation{*constellation}
+	if !localizeConstellations(c, bindLanguage(c), constellations) {
+		return
+	}
+	constellation = &constellations[0]
+
 	c.JSON(http.StatusOK, constellation)
 }
 
@@ -65,11 +102,5 @@ func GetConstellationsByRegion(c *gin.Context) {
 		return
 	}
 
-	constellations, err := db.GetConstellationsByRegionID(regionID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, constellations)
+	listConstellations(c, db.ConstellationFilter{RegionIDs: []int{regionID}})
 }
@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/jobs"
+)
+
+type trackedEntityRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// AddTrackedEntity returns a handler that starts tracking a corporation or alliance
+// and triggers a full kill fetch for it.
+func AddTrackedEntity(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request trackedEntityRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		entity := models.TrackedEntity{EntityType: entityType, EntityID: request.ID}
+		err := db.InsertTrackedEntity(&entity)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add " + entityType})
+			return
+		}
+
+		go jobs.FetchAllKillsForEntity(entityType, entity.EntityID)
+
+		c.JSON(http.StatusCreated, entity)
+	}
+}
+
+// RemoveTrackedEntity returns a handler that stops tracking a corporation or alliance.
+// Kills already stored for it are kept.
+func RemoveTrackedEntity(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + entityType + " ID"})
+			return
+		}
+
+		err = db.DeleteTrackedEntity(entityType, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetTrackedEntities returns a handler listing all tracked corporations or alliances.
+func GetTrackedEntities(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entities, err := db.GetTrackedEntities(entityType)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, entities)
+	}
+}
+
+// GetEntityKills returns a handler serving paginated kills attributed to a corporation or alliance.
+// The shared kill filters apply.
+func GetEntityKills(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + entityType + " ID"})
+			return
+		}
+
+		filter, ok := bindKillFilter(c)
+		if !ok {
+			return
+		}
+		if entityType == models.EntityTypeCorporation {
+			filter.CorporationIDs = []int64{id}
+		} else {
+			filter.AllianceIDs = []int64{id}
+		}
+
+		page, pageSize, ok := bindPagination(c, 20)
+		if !ok {
+			return
+		}
+
+		kills, totalCount, err := db.GetKills(filter, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		response := models.PaginatedResponse{
+			Data:       kills,
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: int(totalCount),
+			TotalPages: int(math.Ceil(float64(totalCount) / float64(pageSize))),
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetEntityStats returns a handler serving kill rollups per corporation or alliance,
+// accepting the same filters and breakdown as /characters/stats.
+func GetEntityStats(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, ok := bindKillFilter(c)
+		if !ok {
+			return
+		}
+
+		bySpace, ok := bindSpaceBreakdown(c)
+		if !ok {
+			return
+		}
+
+		stats, err := db.GetEntityStats(entityType, filter, bySpace)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+)
+
+// respondError responds with a database error: 501 when the query needs a feature the
+// configured backend lacks, such as SQLite running a Postgres-only query, and 500 otherwise.
+func respondError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, db.ErrUnsupported) {
+		status = http.StatusNotImplemented
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+)
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := map[error]int{
+		db.ErrUnsupported: http.StatusNotImplemented,
+		fmt.Errorf("search: %w", db.ErrUnsupported): http.StatusNotImplemented,
+		errors.New("connection refused"):            http.StatusInternalServerError,
+	}
+	for err, want := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		respondError(c, err)
+		if w.Code != want {
+			t.Errorf("respondError(%v) status = %d, want %d", err, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/export"
+)
+
+// negotiateFormat resolves the response format from ?format= or the Accept header,
+// responding with 400 on an unknown format.
+func negotiateFormat(c *gin.Context) (export.Format, bool) {
+	format, err := export.Negotiate(c.Query("format"), c.GetHeader("Accept"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return format, true
+}
+
+// exportKills streams every kill matching the filter, ignoring pagination. Tabular formats
+// get flattened rows; NDJSON keeps the full kill document per line.
+func exportKills(c *gin.Context, format export.Format, filter db.KillFilter, name string) {
+	if format.Tabular() {
+		streamExport(c, format, name, func(write func(export.KillRow) error) error {
+			return db.StreamKills(filter, func(kill *models.Kill) error {
+				return write(export.NewKillRow(kill))
+			})
+		})
+		return
+	}
+
+	streamExport(c, format, name, func(write func(*models.Kill) error) error {
+		return db.StreamKills(filter, write)
+	})
+}
+
+func exportCharacterStats(c *gin.Context, format export.Format, filter db.KillFilter, bySpace bool) {
+	streamExport(c, format, "character_stats", func(write func(db.CharacterStats) error) error {
+		return db.StreamCharacterStats(filter, bySpace, write)
+	})
+}
+
+// streamExport writes rows produced by stream directly to the response. Once streaming has
+// started the status can no longer change, so failures are only logged.
+func streamExport[T any](c *gin.Context, format export.Format, name string, stream func(write func(T) error) error) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	c.Status(http.StatusOK)
+
+	writer := export.NewWriter[T](format, c.Writer)
+	err := stream(writer.Write)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Printf("Error exporting %s as %s: %v", name, format, err)
+	}
+}
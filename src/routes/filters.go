@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+)
+
+// bindKillFilter parses the shared kill filter parameters, responding with 400 on invalid input.
+func bindKillFilter(c *gin.Context) (db.KillFilter, bool) {
+	filter, err := db.ParseKillFilter(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filter, false
+	}
+	return filter, true
+}
+
+// bindPagination parses page and pageSize, responding with 400 on invalid input.
+func bindPagination(c *gin.Context, defaultPageSize int) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page: expected a positive integer"})
+		return 0, 0, false
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pageSize: expected an integer between 1 and 500"})
+		return 0, 0, false
+	}
+
+	return page, pageSize, true
+}
+
+// bindSpaceBreakdown parses breakdown, which splits stats rows by space class when set to "space".
+func bindSpaceBreakdown(c *gin.Context) (bool, bool) {
+	switch c.Query("breakdown") {
+	case "":
+		return false, true
+	case "space":
+		return true, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid breakdown: expected space"})
+		return false, false
+	}
+}
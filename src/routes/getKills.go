@@ -1,22 +1,45 @@
 package routes
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/fitting"
+	"github.com/tadeasf/eve-ran/src/killmail"
 )
 
+// GetCharacterKillmails retrieves all stored kills of a character
+// @Summary Get character killmails
+// @Description Fetch all stored kills for a character, narrowed by the shared kill filters
+// @Tags characters
+// @Produce json
+// @Param id path int true "Character ID"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param systemID query []int false "Solar system IDs"
+// @Param regionID query []int false "Region IDs"
+// @Success 200 {array} models.Kill
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /characters/{id}/killmails [get]
 func GetCharacterKillmails(c *gin.Context) {
-	characterID, _ := strconv.ParseInt(c.Param("id"), 10, 64)
-	startTime, _ := time.Parse(time.RFC3339, c.Query("start_time"))
-	endTime, _ := time.Parse(time.RFC3339, c.Query("end_time"))
-	systemID, _ := strconv.ParseInt(c.Query("system_id"), 10, 64)
-	regionID, _ := strconv.ParseInt(c.Query("region_id"), 10, 64)
+	characterID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid character ID"})
+		return
+	}
+
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+	filter.CharacterIDs = []int64{characterID}
 
-	kills, err := db.GetCharacterKillmails(characterID, startTime, endTime, systemID, regionID)
+	kills, err := db.GetCharacterKillmails(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -24,3 +47,126 @@ func GetCharacterKillmails(c *gin.Context) {
 
 	c.JSON(http.StatusOK, kills)
 }
+
+// GetKill retrieves a single kill with every reference resolved
+// @Summary Get a kill
+// @Description Fetch a single stored kill by killmail ID, with system, constellation and region names, participant and type names, items grouped by slot and zKillboard/ESI links
+// @Tags kills
+// @Produce json
+// @Param killmailID path int true "Killmail ID"
+// @Success 200 {object} killmail.Detail
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kills/{killmailID} [get]
+func GetKill(c *gin.Context) {
+	kill, ok := loadKill(c)
+	if !ok {
+		return
+	}
+
+	detail, err := killmail.BuildDetail(kill)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// GetKillTimeseries counts kills per day
+// @Summary Get kills per day
+// @Description Count kills and sum their value per UTC day. Ranges covering whole days that filter only by character, location or ship type are served from daily rollups.
+// @Tags kills
+// @Produce json
+// @Param characterID query []int false "Tracked character IDs"
+// @Param corporationID query []int false "Corporation IDs"
+// @Param allianceID query []int false "Alliance IDs"
+// @Param systemID query []int false "Solar system IDs"
+// @Param constellationID query []int false "Constellation IDs"
+// @Param regionID query []int false "Region IDs"
+// @Param shipTypeID query []int false "Victim ship type IDs"
+// @Param space query []string false "Space classes or groups (kspace, wormhole)"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param minValue query number false "Minimum total value"
+// @Param maxValue query number false "Maximum total value"
+// @Param solo query bool false "Solo kills only (true) or excluded (false)"
+// @Param npc query bool false "NPC kills only (true) or excluded (false)"
+// @Param awox query bool false "Awox kills only (true) or excluded (false)"
+// @Success 200 {array} db.DailyStats
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kills/timeseries [get]
+func GetKillTimeseries(c *gin.Context) {
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := db.GetKillTimeseries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetKillFit reconstructs the victim's fitting
+// @Summary Get a kill's fitting
+// @Description Reconstruct the victim's fitting from killmail items as EFT text, ship DNA or slot-grouped JSON
+// @Tags kills
+// @Produce json,plain
+// @Param killmailID path int true "Killmail ID"
+// @Param format query string false "Output format" Enums(json, eft, dna)
+// @Success 200 {object} fitting.Fitting
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /kills/{killmailID}/fit [get]
+func GetKillFit(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "eft" && format != "dna" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format: expected one of json, eft, dna"})
+		return
+	}
+
+	kill, ok := loadKill(c)
+	if !ok {
+		return
+	}
+
+	types, err := db.GetTypeInfos(fitting.TypeIDs(kill))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	fit := fitting.Decode(kill, types)
+	switch format {
+	case "eft":
+		c.String(http.StatusOK, fit.EFT(fmt.Sprintf("Killmail %d", kill.KillmailID)))
+	case "dna":
+		c.String(http.StatusOK, fit.DNA())
+	default:
+		c.JSON(http.StatusOK, fit)
+	}
+}
+
+func loadKill(c *gin.Context) (*models.Kill, bool) {
+	killmailID, err := strconv.ParseInt(c.Param("killmailID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid killmail ID"})
+		return nil, false
+	}
+
+	kill, err := db.GetKillByKillmailID(killmailID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if kill == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kill not found"})
+		return nil, false
+	}
+	return kill, true
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
 	"github.com/tadeasf/eve-ran/src/services"
 )
 
@@ -16,25 +17,53 @@ func FetchAndStoreItems(c *gin.Context) {
 		return
 	}
 
-	for _, item := range items {
-		err = db.UpsertESIItem(item)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	err = db.UpsertESIItems(derefAll(items))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
+	db.InvalidateUniverseCache()
+
 	c.JSON(http.StatusOK, gin.H{"message": "Items fetched and stored successfully", "count": len(items)})
 }
 
+// GetAllItems lists item types
+// @Summary List items
+// @Description Fetch item types, optionally filtered, projected and paginated. Responses carry an ETag and honour If-None-Match.
+// @Tags items
+// @Produce json
+// @Param groupID query []int false "Group IDs"
+// @Param published query bool false "Only published or unpublished types"
+// @Param lang query string false "Response language; defaults to Accept-Language, then en"
+// @Param fields query string false "Comma-separated fields to return"
+// @Param page query int false "Page number; paginates the response when set"
+// @Param pageSize query int false "Page size; paginates the response when set"
+// @Success 200 {array} models.ESIItem
+// @Success 304
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /items [get]
 func GetAllItems(c *gin.Context) {
-	items, err := db.GetAllESIItems()
+	filter, ok := bindUniverseFilter(c, db.ParseItemFilter)
+	if !ok {
+		return
+	}
+
+	q, ok := bindUniverseQuery[models.ESIItem](c)
+	if !ok {
+		return
+	}
+
+	items, total, err := db.ListESIItems(filter, q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	c.JSON(http.StatusOK, items)
+	if !localizeItems(c, bindLanguage(c), items) {
+		return
+	}
+	respondUniverse(c, items, total, q)
 }
 
 func GetItemByTypeID(c *gin.Context) {
@@ -56,5 +85,11 @@ func GetItemByTypeID(c *gin.Context) {
 		return
 	}
 
+	items := []models.ESIItem{*item}
+	if !localizeItems(c, bindLanguage(c), items) {
+		return
+	}
+	item = &items[0]
+
 	c.JSON(http.StatusOK, item)
 }
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/i18n"
+)
+
+// bindLanguage negotiates the language of a universe response from lang and Accept-Language,
+// announcing the choice in Content-Language.
+func bindLanguage(c *gin.Context) string {
+	lang := i18n.Negotiate(c.Query("lang"), c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", lang)
+	c.Header("Vary", "Accept-Language")
+	return lang
+}
+
+// localize replaces the names of rows with their stored translation, keeping the English
+// name where none is stored yet. It responds with 500 and returns false on failure.
+func localize[T any](c *gin.Context, lang, kind string, rows []T, id func(*T) int, apply func(*T, models.LocalizedName)) bool {
+	if lang == i18n.Default || len(rows) == 0 {
+		return true
+	}
+
+	ids := make([]int, len(rows))
+	for i := range rows {
+		ids[i] = id(&rows[i])
+	}
+
+	names, err := db.GetLocalizedNames(kind, ids, lang)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	for i := range rows {
+		if name, ok := names[id(&rows[i])]; ok {
+			apply(&rows[i], name)
+		}
+	}
+	return true
+}
+
+func localizeRegions(c *gin.Context, lang string, regions []models.Region) bool {
+	return localize(c, lang, models.LocalizedRegion, regions,
+		func(r *models.Region) int { return r.RegionID },
+		func(r *models.Region, name models.LocalizedName) {
+			r.Name = name.Name
+			if name.Description != "" {
+				r.Description = name.Description
+			}
+		})
+}
+
+func localizeConstellations(c *gin.Context, lang string, constellations []models.Constellation) bool {
+	return localize(c, lang, models.LocalizedConstellation, constellations,
+		func(c *models.Constellation) int { return c.ConstellationID },
+		func(c *models.Constellation, name models.LocalizedName) { c.Name = name.Name })
+}
+
+func localizeSystems(c *gin.Context, lang string, systems []models.System) bool {
+	return localize(c, lang, models.LocalizedSystem, systems,
+		func(s *models.System) int { return s.SystemID },
+		func(s *models.System, name models.LocalizedName) { s.Name = name.Name })
+}
+
+func localizeItems(c *gin.Context, lang string, items []models.ESIItem) bool {
+	return localize(c, lang, models.LocalizedType, items,
+		func(i *models.ESIItem) int { return i.TypeID },
+		func(i *models.ESIItem, name models.LocalizedName) {
+			i.Name = name.Name
+			if name.Description != "" {
+				i.Description = name.Description
+			}
+		})
+}
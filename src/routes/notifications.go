@@ -0,0 +1,213 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/notifications"
+)
+
+type notificationRuleRequest struct {
+	Name               string          `json:"name" binding:"required"`
+	WebhookURL         string          `json:"webhook_url" binding:"required,url"`
+	Format             string          `json:"format"`
+	Enabled            *bool           `json:"enabled"`
+	MinTotalValue      float64         `json:"min_total_value"`
+	VictimShipGroupIDs models.IntArray `json:"victim_ship_group_ids"`
+	RegionIDs          models.IntArray `json:"region_ids"`
+	SystemIDs          models.IntArray `json:"system_ids"`
+	CharacterIDs       models.IntArray `json:"character_ids"`
+	SoloOnly           bool            `json:"solo_only"`
+}
+
+// apply copies the request onto the rule. Rules are enabled unless explicitly disabled.
+func (r notificationRuleRequest) apply(rule *models.NotificationRule) bool {
+	switch r.Format {
+	case "":
+		r.Format = models.WebhookFormatGeneric
+	case models.WebhookFormatGeneric, models.WebhookFormatDiscord, models.WebhookFormatSlack:
+	default:
+		return false
+	}
+
+	rule.Name = r.Name
+	rule.WebhookURL = r.WebhookURL
+	rule.Format = r.Format
+	rule.Enabled = r.Enabled == nil || *r.Enabled
+	rule.MinTotalValue = r.MinTotalValue
+	rule.VictimShipGroupIDs = r.VictimShipGroupIDs
+	rule.RegionIDs = r.RegionIDs
+	rule.SystemIDs = r.SystemIDs
+	rule.CharacterIDs = r.CharacterIDs
+	rule.SoloOnly = r.SoloOnly
+	return true
+}
+
+// GetNotificationRules retrieves all notification rules
+// @Summary Get notification rules
+// @Description Fetch all webhook notification rules
+// @Tags notifications
+// @Produce json
+// @Success 200 {array} models.NotificationRule
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/rules [get]
+func GetNotificationRules(c *gin.Context) {
+	rules, err := db.GetAllNotificationRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetNotificationRule retrieves a single notification rule
+// @Summary Get a notification rule
+// @Tags notifications
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} models.NotificationRule
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/rules/{id} [get]
+func GetNotificationRule(c *gin.Context) {
+	rule, ok := loadNotificationRule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateNotificationRule creates a notification rule
+// @Summary Create a notification rule
+// @Description Create a rule that posts matching newly ingested kills to a generic, Discord or Slack webhook
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param rule body notificationRuleRequest true "Notification rule"
+// @Success 201 {object} models.NotificationRule
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/rules [post]
+func CreateNotificationRule(c *gin.Context) {
+	var request notificationRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rule models.NotificationRule
+	if !request.apply(&rule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook format"})
+		return
+	}
+
+	if err := db.CreateNotificationRule(&rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateNotificationRule replaces a notification rule
+// @Summary Update a notification rule
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param rule body notificationRuleRequest true "Notification rule"
+// @Success 200 {object} models.NotificationRule
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/rules/{id} [put]
+func UpdateNotificationRule(c *gin.Context) {
+	rule, ok := loadNotificationRule(c)
+	if !ok {
+		return
+	}
+
+	var request notificationRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !request.apply(rule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook format"})
+		return
+	}
+
+	if err := db.UpdateNotificationRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteNotificationRule removes a notification rule
+// @Summary Delete a notification rule
+// @Tags notifications
+// @Param id path int true "Rule ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/rules/{id} [delete]
+func DeleteNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	if err := db.DeleteNotificationRule(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TestNotificationRule sends the latest stored kill to a rule's webhook
+// @Summary Test a notification rule
+// @Description Deliver the most recent kill to the rule's webhook regardless of its criteria
+// @Tags notifications
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /notifications/rules/{id}/test [post]
+func TestNotificationRule(c *gin.Context) {
+	rule, ok := loadNotificationRule(c)
+	if !ok {
+		return
+	}
+
+	if err := notifications.SendTestNotification(*rule); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test notification delivered"})
+}
+
+func loadNotificationRule(c *gin.Context) (*models.NotificationRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return nil, false
+	}
+
+	rule, err := db.GetNotificationRule(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if rule == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification rule not found"})
+		return nil, false
+	}
+	return rule, true
+}
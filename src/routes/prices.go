@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/jobs"
+	"github.com/tadeasf/eve-ran/src/valuation"
+)
+
+// FetchAndStorePrices triggers market price ingestion
+// @Summary Fetch market prices
+// @Description Start ingesting ESI market prices and regional history in the background
+// @Tags prices
+// @Produce json
+// @Success 202 {object} map[string]string
+// @Failure 501 {object} models.ErrorResponse
+// @Router /prices/fetch [post]
+func FetchAndStorePrices(c *gin.Context) {
+	if !db.IsPostgres() {
+		respondError(c, db.ErrUnsupported)
+		return
+	}
+
+	go jobs.FetchAndStorePrices()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Price fetch started"})
+}
+
+// GetTypePriceHistory retrieves the stored price history of a type
+// @Summary Get type price history
+// @Description Fetch daily prices for a type. regionID 0 (the default) returns ESI global prices.
+// @Tags prices
+// @Produce json
+// @Param typeID path int true "Type ID"
+// @Param regionID query int false "Region ID"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {array} models.TypePrice
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /prices/{typeID} [get]
+func GetTypePriceHistory(c *gin.Context) {
+	typeID, err := strconv.Atoi(c.Param("typeID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type ID"})
+		return
+	}
+
+	regionID, err := strconv.Atoi(c.DefaultQuery("regionID", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid region ID"})
+		return
+	}
+
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	prices, err := db.GetTypePriceHistory(typeID, regionID, filter.StartTime, filter.EndTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, prices)
+}
+
+// RevalueKills queues kills for revaluation
+// @Summary Revalue kills
+// @Description Recompute computed_*_value for all kills matching the shared kill filters
+// @Tags prices
+// @Produce json
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /kills/revalue [post]
+func RevalueKills(c *gin.Context) {
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	count, err := db.ResetKillValuations(filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	go func() {
+		if _, err := valuation.ValuePendingKills(); err != nil {
+			log.Printf("Error revaluing kills: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Revaluation started", "count": count})
+}
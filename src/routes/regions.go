@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
 	"github.com/tadeasf/eve-ran/src/services"
 )
 
@@ -15,31 +16,45 @@ func FetchAndStoreRegions(c *gin.Context) {
 		return
 	}
 
-	for _, region := range regions {
-		err = db.UpsertRegion(region)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	err = db.UpsertRegions(derefAll(regions))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
+	db.InvalidateUniverseCache()
+
 	c.JSON(http.StatusOK, gin.H{"message": "Regions fetched and stored successfully", "count": len(regions)})
 }
 
 // GetAllRegions retrieves all regions from the database
 // @Summary Get all regions
-// @Description Fetch all regions from the database
+// @Description Fetch all regions from the database, optionally projected and paginated. Responses carry an ETag and honour If-None-Match.
 // @Tags regions
 // @Accept json
 // @Produce json
+// @Param lang query string false "Response language; defaults to Accept-Language, then en"
+// @Param fields query string false "Comma-separated fields to return"
+// @Param page query int false "Page number; paginates the response when set"
+// @Param pageSize query int false "Page size; paginates the response when set"
 // @Success 200 {array} models.Region
+// @Success 304
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /regions [get]
 func GetAllRegions(c *gin.Context) {
-	regions, err := db.GetAllRegions()
+	q, ok := bindUniverseQuery[models.Region](c)
+	if !ok {
+		return
+	}
+
+	regions, total, err := db.ListRegions(q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, regions)
+	if !localizeRegions(c, bindLanguage(c), regions) {
+		return
+	}
+	respondUniverse(c, regions, total, q)
 }
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+)
+
+// Search looks up names across the universe and resolved entities
+// @Summary Search names
+// @Description Find systems, constellations, regions, items and resolved characters, corporations and alliances by name prefix or fuzzy match. Exact matches rank first, then prefix matches, then by similarity.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search text"
+// @Param types query []string false "Types to search (system, constellation, region, item, character, corporation, alliance); all by default"
+// @Param limit query int false "Maximum results" default(20)
+// @Success 200 {array} db.SearchResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /search [get]
+func Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid q: must not be empty"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: expected an integer between 1 and 100"})
+		return
+	}
+
+	types := db.SearchTypes
+	if param := c.QueryArray("types"); len(param) > 0 {
+		types = nil
+		valid := make(map[string]bool, len(db.SearchTypes))
+		for _, t := range db.SearchTypes {
+			valid[t] = true
+		}
+		for _, value := range param {
+			for _, t := range strings.Split(value, ",") {
+				t = strings.ToLower(strings.TrimSpace(t))
+				if t == "" {
+					continue
+				}
+				if _, ok := valid[t]; !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid types: unknown type " + strconv.Quote(t)})
+					return
+				}
+				if valid[t] {
+					valid[t] = false
+					types = append(types, t)
+				}
+			}
+		}
+	}
+
+	results, err := db.Search(q, types, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+)
+
+// GetCharacterShips retrieves the ship types a character flies
+// @Summary Get character ship usage
+// @Description Fetch the ship types a character was seen attacking in, with kill counts, average damage share, final-blow rate, first/last use and weapons used
+// @Tags characters
+// @Produce json
+// @Param id path int true "Character ID"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param regionID query []int false "Region IDs"
+// @Success 200 {array} db.ShipUsage
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /characters/{id}/ships [get]
+func GetCharacterShips(c *gin.Context) {
+	characterID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid character ID"})
+		return
+	}
+
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+
+	ships, err := db.GetCharacterShipUsage(characterID, filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ships)
+}
+
+// GetEntityDoctrines returns a handler serving the ship groups a corporation's or alliance's
+// members fly, with the ship types used within each group.
+func GetEntityDoctrines(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + entityType + " ID"})
+			return
+		}
+
+		filter, ok := bindKillFilter(c)
+		if !ok {
+			return
+		}
+
+		groups, err := db.GetDoctrineUsage(entityType, id, filter)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, groups)
+	}
+}
@@ -0,0 +1,226 @@
+package routes
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/stream"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	// The dashboard is served from a different origin than the API.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// StreamKills pushes newly ingested kills as Server-Sent Events
+// @Summary Live kill feed (SSE)
+// @Description Stream newly ingested kills as Server-Sent Events. Each event carries the killmail ID as its id,
+// @Description so reconnecting clients resume via the Last-Event-ID header or the lastKillmailID parameter.
+// @Description Resuming replays every matching kill ingested after that one, in ingestion order.
+// @Tags stream
+// @Produce text/event-stream
+// @Param characterID query []int false "Character IDs"
+// @Param regionID query []int false "Region IDs"
+// @Param minValue query number false "Minimum total value"
+// @Param lastKillmailID query int false "Resume with the kills ingested after this one"
+// @Success 200 {object} models.Kill
+// @Failure 400 {object} models.ErrorResponse
+// @Router /stream/kills [get]
+func StreamKills(c *gin.Context) {
+	filter, lastKillmailID, err := parseStreamParams(c, c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub := stream.Subscribe(filter)
+	defer stream.Unsubscribe(sub)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	sent := make(map[int64]bool)
+	err = replayEvents(filter, lastKillmailID, func(event stream.Event) bool {
+		c.Render(-1, killSSEvent(event))
+		sent[event.Kill.KillmailID] = true
+		return c.Request.Context().Err() == nil
+	})
+	if err != nil {
+		if len(sent) == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		} else {
+			c.Render(-1, sse.Event{Event: "error", Data: gin.H{"error": err.Error()}})
+		}
+		return
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-sub.Lagged():
+			c.Render(-1, sse.Event{Event: "lagged", Data: gin.H{"error": "Client too slow, reconnect with Last-Event-ID"}})
+			return false
+		case event := <-sub.Events():
+			if !sent[event.Kill.KillmailID] {
+				c.Render(-1, killSSEvent(event))
+			}
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+func killSSEvent(event stream.Event) sse.Event {
+	return sse.Event{
+		Id:    strconv.FormatInt(event.Kill.KillmailID, 10),
+		Event: "kill",
+		Data:  event.Kill,
+	}
+}
+
+// StreamKillsWebSocket pushes newly ingested kills over a WebSocket
+// @Summary Live kill feed (WebSocket)
+// @Description Same feed and filters as /stream/kills, delivered as JSON messages of the form {"type": "kill", "kill": {...}}
+// @Tags stream
+// @Param characterID query []int false "Character IDs"
+// @Param regionID query []int false "Region IDs"
+// @Param minValue query number false "Minimum total value"
+// @Param lastKillmailID query int false "Resume with the kills ingested after this one"
+// @Success 101 "Switching Protocols"
+// @Failure 400 {object} models.ErrorResponse
+// @Router /stream/kills/ws [get]
+func StreamKillsWebSocket(c *gin.Context) {
+	filter, lastKillmailID, err := parseStreamParams(c, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Error upgrading WebSocket connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := stream.Subscribe(filter)
+	defer stream.Unsubscribe(sub)
+
+	// Drain client messages so close frames are processed and disconnects are noticed.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(message interface{}) bool {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(message) == nil
+	}
+
+	sent := make(map[int64]bool)
+	writeFailed := false
+	err = replayEvents(filter, lastKillmailID, func(event stream.Event) bool {
+		if !write(gin.H{"type": "kill", "kill": event.Kill}) {
+			writeFailed = true
+			return false
+		}
+		sent[event.Kill.KillmailID] = true
+		return true
+	})
+	if err != nil {
+		write(gin.H{"type": "error", "error": err.Error()})
+		return
+	}
+	if writeFailed {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-sub.Lagged():
+			write(gin.H{"type": "lagged", "error": "Client too slow, reconnect with lastKillmailID"})
+			return
+		case event := <-sub.Events():
+			if sent[event.Kill.KillmailID] {
+				continue
+			}
+			if !write(gin.H{"type": "kill", "kill": event.Kill}) {
+				return
+			}
+		case <-heartbeat.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)) != nil {
+				return
+			}
+		}
+	}
+}
+
+func replayEvents(filter stream.Filter, lastKillmailID int64, fn func(stream.Event) bool) error {
+	if lastKillmailID == 0 {
+		return nil
+	}
+	return stream.Replay(filter, lastKillmailID, fn)
+}
+
+// parseStreamParams reads the stream filters with the shared kill filter parser, so they accept
+// the same forms as the other kill routes. lastEventID, when set, takes precedence over
+// lastKillmailID.
+func parseStreamParams(c *gin.Context, lastEventID string) (stream.Filter, int64, error) {
+	var filter stream.Filter
+
+	killFilter, err := db.ParseKillFilter(c.Request.URL.Query())
+	if err != nil {
+		return filter, 0, err
+	}
+	filter.CharacterIDs = killFilter.CharacterIDs
+	filter.RegionIDs = killFilter.RegionIDs
+	if killFilter.MinValue != nil {
+		filter.MinValue = *killFilter.MinValue
+	}
+
+	resumeFrom := lastEventID
+	if resumeFrom == "" {
+		resumeFrom = c.Query("lastKillmailID")
+	}
+	var lastKillmailID int64
+	if resumeFrom != "" {
+		id, err := strconv.ParseInt(resumeFrom, 10, 64)
+		if err != nil {
+			return filter, 0, &db.FilterError{Param: "lastKillmailID", Message: fmt.Sprintf("%q is not a valid ID", resumeFrom)}
+		}
+		lastKillmailID = id
+	}
+
+	return filter, lastKillmailID, nil
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+)
+
+func TestParseStreamParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(query, lastEventID string) (string, int64, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/stream/kills?"+query, nil)
+		filter, lastKillmailID, err := parseStreamParams(c, lastEventID)
+		return fmt.Sprintf("%v %v %v", filter.CharacterIDs, filter.RegionIDs, filter.MinValue), lastKillmailID, err
+	}
+
+	filter, last, err := parse("characterID=1,2&regionID=10000002&minValue=1e6&lastKillmailID=5", "")
+	if err != nil || filter != "[1 2] [10000002] 1e+06" || last != 5 {
+		t.Errorf("parseStreamParams = %q, %d, %v", filter, last, err)
+	}
+	if _, last, _ := parse("lastKillmailID=5", "7"); last != 7 {
+		t.Errorf("Last-Event-ID resumed from %d, want 7", last)
+	}
+
+	for _, query := range []string{"characterID=x", "regionID=1.5", "minValue=-1", "lastKillmailID=x"} {
+		var filterErr *db.FilterError
+		if _, _, err := parse(query, ""); !errors.As(err, &filterErr) {
+			t.Errorf("parseStreamParams(%q) error = %v, want a FilterError", query, err)
+		}
+	}
+}
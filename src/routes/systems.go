@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
 	"github.com/tadeasf/eve-ran/src/services"
 )
 
@@ -16,25 +17,65 @@ func FetchAndStoreSystems(c *gin.Context) {
 		return
 	}
 
-	for _, system := range systems {
-		err = db.UpsertSystem(system)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	err = db.UpsertSystems(derefAll(systems))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
+	err = db.ClassifySystems()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	db.InvalidateUniverseCache()
+
 	c.JSON(http.StatusOK, gin.H{"message": "Systems fetched and stored successfully", "count": len(systems)})
 }
 
+// GetAllSystems lists systems
+// @Summary List systems
+// @Description Fetch systems, optionally filtered, projected and paginated. Responses carry an ETag and honour If-None-Match.
+// @Tags systems
+// @Produce json
+// @Param regionID query []int false "Region IDs"
+// @Param constellationID query []int false "Constellation IDs"
+// @Param space query []string false "Space classes or groups (kspace, wormhole)"
+// @Param minSecurity query number false "Minimum security status"
+// @Param maxSecurity query number false "Maximum security status"
+// @Param lang query string false "Response language; defaults to Accept-Language, then en"
+// @Param fields query string false "Comma-separated fields to return"
+// @Param page query int false "Page number; paginates the response when set"
+// @Param pageSize query int false "Page size; paginates the response when set"
+// @Success 200 {array} models.System
+// @Success 304
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /systems [get]
 func GetAllSystems(c *gin.Context) {
-	systems, err := db.GetAllSystems()
+	filter, ok := bindUniverseFilter(c, db.ParseSystemFilter)
+	if !ok {
+		return
+	}
+	listSystems(c, filter)
+}
+
+func listSystems(c *gin.Context, filter db.SystemFilter) {
+	q, ok := bindUniverseQuery[models.System](c)
+	if !ok {
+		return
+	}
+
+	systems, total, err := db.ListSystems(filter, q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	c.JSON(http.StatusOK, systems)
+	if !localizeSystems(c, bindLanguage(c), systems) {
+		return
+	}
+	respondUniverse(c, systems, total, q)
 }
 
 func GetSystemByID(c *gin.Context) {
@@ -55,6 +96,12 @@ func GetSystemByID(c *gin.Context) {
 		return
 	}
 
+	systems := []models.System{*system}
+	if !localizeSystems(c, bindLanguage(c), systems) {
+		return
+	}
+	system = &systems[0]
+
 	c.JSON(http.StatusOK, system)
 }
 
@@ -65,11 +112,10 @@ func GetSystemsByRegion(c *gin.Context) {
 		return
 	}
 
-	systems, err := db.GetSystemsByRegionID(regionID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	filter, ok := bindUniverseFilter(c, db.ParseSystemFilter)
+	if !ok {
 		return
 	}
-
-	c.JSON(http.StatusOK, systems)
+	filter.RegionIDs = []int{regionID}
+	listSystems(c, filter)
 }
@@ -0,0 +1,139 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+// jsonFields maps the JSON names of T's fields to their index. Universe models use their
+// column names as JSON names, so the keys double as selectable columns.
+func jsonFields[T any]() map[string]int {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// bindUniverseQuery parses fields, page and pageSize for a listing of T. Listings are only
+// paginated when page or pageSize is given, so existing clients keep receiving plain arrays.
+func bindUniverseQuery[T any](c *gin.Context) (db.UniverseQuery, bool) {
+	var q db.UniverseQuery
+
+	if param := c.Query("fields"); param != "" {
+		known := jsonFields[T]()
+		for _, field := range strings.Split(param, ",") {
+			field = strings.TrimSpace(field)
+			if _, ok := known[field]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid fields: unknown field %q", field)})
+				return q, false
+			}
+			q.Fields = append(q.Fields, field)
+		}
+	}
+
+	if c.Query("page") != "" || c.Query("pageSize") != "" {
+		page, pageSize, ok := bindPagination(c, 100)
+		if !ok {
+			return q, false
+		}
+		q.Page, q.PageSize = page, pageSize
+	}
+
+	return q, true
+}
+
+// respondUniverse writes a universe listing, projected to the requested fields and wrapped
+// in a PaginatedResponse when paginated.
+func respondUniverse[T any](c *gin.Context, rows []T, total int64, q db.UniverseQuery) {
+	var data interface{} = rows
+	if len(q.Fields) > 0 {
+		index := jsonFields[T]()
+		projected := make([]map[string]interface{}, len(rows))
+		for i := range rows {
+			v := reflect.ValueOf(rows[i])
+			row := make(map[string]interface{}, len(q.Fields))
+			for _, field := range q.Fields {
+				row[field] = v.Field(index[field]).Interface()
+			}
+			projected[i] = row
+		}
+		data = projected
+	}
+
+	if q.PageSize > 0 {
+		data = models.PaginatedResponse{
+			Data:       data,
+			Page:       q.Page,
+			PageSize:   q.PageSize,
+			TotalItems: int(total),
+			TotalPages: int(math.Ceil(float64(total) / float64(q.PageSize))),
+		}
+	}
+
+	respondWithETag(c, data)
+}
+
+// respondWithETag writes data as JSON with a strong ETag over the body, answering 304 when the
+// client already holds the same representation.
+func respondWithETag(c *gin.Context, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bindUniverseFilter parses a universe filter, responding with 400 on invalid input.
+func bindUniverseFilter[F any](c *gin.Context, parse func(url.Values) (F, error)) (F, bool) {
+	filter, err := parse(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filter, false
+	}
+	return filter, true
+}
+
+// derefAll copies the values of rows fetched from ESI for the batch upserts.
+func derefAll[T any](rows []*T) []T {
+	values := make([]T, len(rows))
+	for i, row := range rows {
+		values[i] = *row
+	}
+	return values
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/tadeasf/eve-ran/src/db"
 	"github.com/tadeasf/eve-ran/src/db/models"
+	"github.com/tadeasf/eve-ran/src/export"
 	"github.com/tadeasf/eve-ran/src/jobs"
 )
 
@@ -92,7 +93,12 @@ func GetCharacterKills(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	// zKillboard pages hold up to 200 kills and cannot be resized.
+	page, _, ok := bindPagination(c, 200)
+	if !ok {
+		return
+	}
+
 	kills, err := fetchKillsFromZKillboard(id, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -167,15 +173,15 @@ func fetchKillsFromZKillboard(characterID int64, page int) ([]models.Kill, error
 	return kills, nil
 }
 
+// storeKills writes the kills through the ingestion path, so partitions, rollups, cached
+// responses and kill listeners are kept up to date. Stored kills only take the zKillboard
+// columns of the list rows; new ones are hydrated from ESI first.
 func storeKills(characterID int64, kills []models.Kill) error {
-	for _, kill := range kills {
-		kill.CharacterID = characterID
-		err := db.DB.Create(&kill).Error
-		if err != nil {
-			return err
-		}
+	pending, err := db.RefreshZKillboardKills(kills)
+	if err != nil {
+		return err
 	}
-	return nil
+	return db.UpsertKills(jobs.HydrateKills(pending, models.EntityTypeCharacter, characterID))
 }
 
 // GetCharacterKillsFromDB retrieves character kills from the database
@@ -183,10 +189,14 @@ func storeKills(characterID int64, kills []models.Kill) error {
 // @Description Fetch kills for a character from the database
 // @Tags characters
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/vnd.apache.parquet
 // @Param id path int true "Character ID"
 // @Param page query int false "Page number"
 // @Param pageSize query int false "Page size"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param regionID query []int false "Region IDs"
+// @Param format query string false "Response format, overrides the Accept header" Enums(json, csv, ndjson, parquet)
 // @Success 200 {object} models.PaginatedResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
@@ -198,16 +208,27 @@ func GetCharacterKillsFromDB(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+	filter.CharacterIDs = []int64{id}
 
-	kills, err := db.GetKillsForCharacter(id, page, pageSize)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		exportKills(c, format, filter, fmt.Sprintf("character_%d_kills", id))
+		return
+	}
+
+	page, pageSize, ok := bindPagination(c, 20)
+	if !ok {
 		return
 	}
 
-	totalItems, err := db.GetTotalKillsForCharacter(id)
+	kills, totalItems, err := db.GetKills(filter, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -231,12 +252,16 @@ func GetCharacterKillsFromDB(c *gin.Context) {
 // @Description Fetch kills for a region from the database
 // @Tags kills
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/vnd.apache.parquet
 // @Param regionID path int true "Region ID"
 // @Param page query int false "Page number"
 // @Param pageSize query int false "Page size"
-// @Param startDate query string false "Start date (YYYY-MM-DD)"
-// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param startDate query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param endDate query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Param sovAllianceID query []int false "Alliances holding sovereignty over the system at the kill time"
+// @Param fwOccupierFactionID query []int false "Factions occupying the warzone system at the kill time"
+// @Param overlay query bool false "Wrap each kill with the sovereignty and faction warfare state of its system at the kill time (JSON only)"
+// @Param format query string false "Response format, overrides the Accept header" Enums(json, csv, ndjson, parquet)
 // @Success 200 {object} models.PaginatedResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
@@ -248,21 +273,45 @@ func GetKillsByRegion(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
-	startDate := c.Query("startDate")
-	endDate := c.Query("endDate")
+	filter, ok := bindKillFilter(c)
+	if !ok {
+		return
+	}
+	filter.RegionIDs = []int{regionID}
+
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		exportKills(c, format, filter, fmt.Sprintf("region_%d_kills", regionID))
+		return
+	}
+
+	page, pageSize, ok := bindPagination(c, 20)
+	if !ok {
+		return
+	}
 
-	kills, totalCount, err := db.GetKillsByRegion(regionID, page, pageSize, startDate, endDate)
+	kills, totalCount, err := db.GetKills(filter, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	var data interface{} = kills
+	if c.Query("overlay") == "true" {
+		data, err = withPoliticalOverlays(kills)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
 
 	response := models.PaginatedResponse{
-		Data:       kills,
+		Data:       data,
 		Page:       page,
 		PageSize:   pageSize,
 		TotalItems: int(totalCount),
@@ -271,3 +320,27 @@ func GetKillsByRegion(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+type killWithOverlay struct {
+	Kill           *models.Kill                  `json:"kill"`
+	Sovereignty    *models.SovereigntyHistory    `json:"sovereignty"`
+	FactionWarfare *models.FactionWarfareHistory `json:"faction_warfare"`
+}
+
+func withPoliticalOverlays(kills []models.Kill) ([]killWithOverlay, error) {
+	overlays, err := db.GetPoliticalOverlays(kills)
+	if err != nil {
+		return nil, err
+	}
+
+	annotated := make([]killWithOverlay, len(kills))
+	for i := range kills {
+		overlay := overlays[kills[i].KillmailID]
+		annotated[i] = killWithOverlay{
+			Kill:           &kills[i],
+			Sovereignty:    overlay.Sovereignty,
+			FactionWarfare: overlay.FactionWarfare,
+		}
+	}
+	return annotated, nil
+}
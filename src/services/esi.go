@@ -1,7 +1,9 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -95,6 +97,48 @@ func FetchSystemInfo(systemID int) (*models.System, error) {
 	return &system, err
 }
 
+func FetchStargateInfo(stargateID int) (*models.Stargate, error) {
+	url := fmt.Sprintf("%s/universe/stargates/%d/?datasource=tranquility", esiBaseURL, stargateID)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ESI returned %s for stargate %d", resp.Status, stargateID)
+	}
+
+	var stargate models.Stargate
+	err = json.Unmarshal(body, &stargate)
+	return &stargate, err
+}
+
+func FetchItemGroupInfo(groupID int) (*models.ItemGroup, error) {
+	url := fmt.Sprintf("%s/universe/groups/%d/?datasource=tranquility&language=en", esiBaseURL, groupID)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ESI returned %s for group %d", resp.Status, groupID)
+	}
+
+	var group models.ItemGroup
+	err = json.Unmarshal(body, &group)
+	return &group, err
+}
+
 func FetchConstellationIDs() ([]int, error) {
 	url := fmt.Sprintf("%s/universe/constellations/?datasource=tranquility", esiBaseURL)
 	resp, err := http.Get(url)
@@ -433,3 +477,85 @@ func fetchKillmailFromESIWithRetry(killmailID int64, hash string) (*models.Kill,
 
 	return &kill, nil
 }
+
+type ESIName struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// ErrUnknownIDs is returned by FetchNames when ESI does not know one of the IDs.
+var ErrUnknownIDs = errors.New("ESI does not know every ID")
+
+// FetchNames resolves up to 1000 IDs of characters, corporations, alliances and other entities.
+// ESI rejects the whole batch with a 404 if any ID is invalid, reported as ErrUnknownIDs.
+func FetchNames(ids []int64) ([]ESIName, error) {
+	body, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/universe/names/?datasource=tranquility", esiBaseURL)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "EVE Ran Application - GitHub: tadeasf/eve-ran")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIDs, string(respBody))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ESI returned %s: %s", resp.Status, string(respBody))
+	}
+
+	var names []ESIName
+	err = json.Unmarshal(respBody, &names)
+	return names, err
+}
+
+// localizedPaths maps a localized kind to its ESI universe endpoint.
+var localizedPaths = map[string]string{
+	models.LocalizedRegion:        "regions",
+	models.LocalizedConstellation: "constellations",
+	models.LocalizedSystem:        "systems",
+	models.LocalizedType:          "types",
+}
+
+// FetchLocalizedName fetches the name and description of a universe object in the given language.
+func FetchLocalizedName(kind string, id int, language string) (*models.LocalizedName, error) {
+	path, ok := localizedPaths[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown localized kind %q", kind)
+	}
+
+	var object struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	url := fmt.Sprintf("%s/universe/%s/%d/?datasource=tranquility&language=%s", esiBaseURL, path, id, language)
+	if err := getESIJSON(url, &object); err != nil {
+		return nil, err
+	}
+
+	return &models.LocalizedName{
+		Kind:        kind,
+		ID:          id,
+		Language:    language,
+		Name:        object.Name,
+		Description: object.Description,
+	}, nil
+}
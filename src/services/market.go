@@ -0,0 +1,67 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type MarketPrice struct {
+	TypeID        int     `json:"type_id"`
+	AdjustedPrice float64 `json:"adjusted_price"`
+	AveragePrice  float64 `json:"average_price"`
+}
+
+type MarketHistoryEntry struct {
+	Date       string  `json:"date"`
+	Average    float64 `json:"average"`
+	Highest    float64 `json:"highest"`
+	Lowest     float64 `json:"lowest"`
+	OrderCount int64   `json:"order_count"`
+	Volume     int64   `json:"volume"`
+}
+
+// FetchMarketPrices returns ESI's current adjusted and average prices for all types.
+func FetchMarketPrices() ([]MarketPrice, error) {
+	url := fmt.Sprintf("%s/markets/prices/?datasource=tranquility", esiBaseURL)
+	var prices []MarketPrice
+	err := getESIJSON(url, &prices)
+	return prices, err
+}
+
+// FetchMarketHistory returns the daily order history of a type in a region (roughly the last 13 months).
+func FetchMarketHistory(regionID, typeID int) ([]MarketHistoryEntry, error) {
+	url := fmt.Sprintf("%s/markets/%d/history/?datasource=tranquility&type_id=%d", esiBaseURL, regionID, typeID)
+	var history []MarketHistoryEntry
+	err := getESIJSON(url, &history)
+	return history, err
+}
+
+func getESIJSON(url string, target interface{}) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "EVE Ran Application - GitHub: tadeasf/eve-ran")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ESI returned %s: %s", resp.Status, string(body))
+	}
+
+	return json.Unmarshal(body, target)
+}
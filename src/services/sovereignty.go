@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+type ESISovereigntySystem struct {
+	SystemID      int   `json:"system_id"`
+	AllianceID    int64 `json:"alliance_id"`
+	CorporationID int64 `json:"corporation_id"`
+	FactionID     int   `json:"faction_id"`
+}
+
+type ESISovereigntyStructure struct {
+	AllianceID                  int64      `json:"alliance_id"`
+	SolarSystemID               int        `json:"solar_system_id"`
+	StructureID                 int64      `json:"structure_id"`
+	StructureTypeID             int        `json:"structure_type_id"`
+	VulnerabilityOccupancyLevel float64    `json:"vulnerability_occupancy_level"`
+	VulnerableStartTime         *time.Time `json:"vulnerable_start_time"`
+	VulnerableEndTime           *time.Time `json:"vulnerable_end_time"`
+}
+
+type ESIFactionWarfareSystem struct {
+	SolarSystemID          int    `json:"solar_system_id"`
+	OwnerFactionID         int    `json:"owner_faction_id"`
+	OccupierFactionID      int    `json:"occupier_faction_id"`
+	Contested              string `json:"contested"`
+	VictoryPoints          int    `json:"victory_points"`
+	VictoryPointsThreshold int    `json:"victory_points_threshold"`
+}
+
+// FetchSovereigntyMap returns the current sovereignty holder of every system that has one.
+func FetchSovereigntyMap() ([]ESISovereigntySystem, error) {
+	var systems []ESISovereigntySystem
+	err := getESIJSON(fmt.Sprintf("%s/sovereignty/map/?datasource=tranquility", esiBaseURL), &systems)
+	return systems, err
+}
+
+func FetchSovereigntyStructures() ([]ESISovereigntyStructure, error) {
+	var structures []ESISovereigntyStructure
+	err := getESIJSON(fmt.Sprintf("%s/sovereignty/structures/?datasource=tranquility", esiBaseURL), &structures)
+	return structures, err
+}
+
+func FetchFactionWarfareSystems() ([]ESIFactionWarfareSystem, error) {
+	var systems []ESIFactionWarfareSystem
+	err := getESIJSON(fmt.Sprintf("%s/fw/systems/?datasource=tranquility", esiBaseURL), &systems)
+	return systems, err
+}
@@ -9,7 +9,22 @@ import (
 )
 
 func FetchKillsFromZKillboard(characterID int64, page int) ([]models.Kill, error) {
-	url := fmt.Sprintf("https://zkillboard.com/api/kills/characterID/%d/page/%d/", characterID, page)
+	kills, err := FetchEntityKillsFromZKillboard(models.EntityTypeCharacter, characterID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range kills {
+		kills[i].CharacterID = characterID
+	}
+
+	return kills, nil
+}
+
+// FetchEntityKillsFromZKillboard fetches a page of kills for a character, corporation or alliance.
+// Only zKillboard metadata is filled in; attribution happens once the ESI killmail is known.
+func FetchEntityKillsFromZKillboard(entityType string, entityID int64, page int) ([]models.Kill, error) {
+	url := fmt.Sprintf("https://zkillboard.com/api/kills/%s/%d/page/%d/", models.ZKillboardModifier(entityType), entityID, page)
 	client := &http.Client{}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -48,7 +63,6 @@ func FetchKillsFromZKillboard(characterID int64, page int) ([]models.Kill, error
 	for _, rawKill := range rawKills {
 		kill := models.Kill{
 			KillmailID:     rawKill.KillmailID,
-			CharacterID:    characterID,
 			LocationID:     rawKill.ZKB.LocationID,
 			Hash:           rawKill.ZKB.Hash,
 			FittedValue:    rawKill.ZKB.FittedValue,
@@ -0,0 +1,183 @@
+package stream
+
+import (
+	"log"
+	"sync"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+const (
+	incomingBufferSize   = 1000
+	subscriberBufferSize = 64
+	replayLimit          = 500
+)
+
+// Filter restricts which kills a subscriber receives. Empty fields match everything.
+type Filter struct {
+	CharacterIDs []int64
+	RegionIDs    []int
+	MinValue     float64
+}
+
+func (f Filter) Matches(event Event) bool {
+	if event.Kill.TotalValue < f.MinValue {
+		return false
+	}
+
+	if len(f.RegionIDs) > 0 {
+		found := false
+		for _, regionID := range f.RegionIDs {
+			if regionID == event.RegionID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.CharacterIDs) > 0 {
+		found := false
+		for _, characterID := range f.CharacterIDs {
+			if event.Kill.InvolvesCharacter(characterID) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Event is a newly ingested kill together with its resolved region.
+type Event struct {
+	Kill     *models.Kill
+	RegionID int
+}
+
+// Subscriber receives matching events until it unsubscribes or falls too far behind.
+type Subscriber struct {
+	filter Filter
+	events chan Event
+	lagged chan struct{}
+}
+
+// Events delivers matching kills in ingestion order.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Lagged is closed when the subscriber was dropped because its buffer filled up.
+// Clients are expected to reconnect and resume from the last killmail ID they saw.
+func (s *Subscriber) Lagged() <-chan struct{} {
+	return s.lagged
+}
+
+type hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	incoming    chan *models.Kill
+}
+
+var defaultHub = &hub{
+	subscribers: make(map[*Subscriber]struct{}),
+	incoming:    make(chan *models.Kill, incomingBufferSize),
+}
+
+// Start registers the hub with the ingestion path and starts broadcasting.
+func Start() {
+	db.OnKillUpserted(func(kill *models.Kill) {
+		k := *kill
+		select {
+		case defaultHub.incoming <- &k:
+		default:
+			log.Printf("Stream queue full, dropping kill %d", kill.KillmailID)
+		}
+	})
+
+	go defaultHub.run()
+}
+
+func (h *hub) run() {
+	for kill := range h.incoming {
+		h.broadcast(newEvent(kill))
+	}
+}
+
+func (h *hub) broadcast(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.lagged)
+		}
+	}
+}
+
+func newEvent(kill *models.Kill) Event {
+	regionID, err := db.GetRegionIDForSystem(kill.SolarSystemID)
+	if err != nil {
+		log.Printf("Error resolving region for system %d: %v", kill.SolarSystemID, err)
+	}
+	return Event{Kill: kill, RegionID: regionID}
+}
+
+func Subscribe(filter Filter) *Subscriber {
+	sub := &Subscriber{
+		filter: filter,
+		events: make(chan Event, subscriberBufferSize),
+		lagged: make(chan struct{}),
+	}
+
+	defaultHub.mu.Lock()
+	defaultHub.subscribers[sub] = struct{}{}
+	defaultHub.mu.Unlock()
+
+	return sub
+}
+
+func Unsubscribe(sub *Subscriber) {
+	defaultHub.mu.Lock()
+	delete(defaultHub.subscribers, sub)
+	defaultHub.mu.Unlock()
+}
+
+// Replay calls fn with the stored kills matching the filter that were ingested after
+// lastKillmailID, in ingestion order, so reconnecting clients can catch up before receiving
+// live events. Kills are read in pages until the client has caught up or fn returns false.
+func Replay(filter Filter, lastKillmailID int64, fn func(Event) bool) error {
+	var minValue *float64
+	if filter.MinValue > 0 {
+		minValue = &filter.MinValue
+	}
+	killFilter := db.KillFilter{RegionIDs: filter.RegionIDs, MinValue: minValue}
+
+	for {
+		kills, err := db.GetKillsIngestedAfter(lastKillmailID, killFilter, filter.CharacterIDs, replayLimit)
+		if err != nil {
+			return err
+		}
+		for i := range kills {
+			if !fn(newEvent(&kills[i])) {
+				return nil
+			}
+		}
+		if len(kills) < replayLimit {
+			return nil
+		}
+		lastKillmailID = kills[len(kills)-1].KillmailID
+	}
+}
@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "eve-ran-stream")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("SQLITE_PATH", filepath.Join(dir, "test.db"))
+	db.InitDB()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func replayed(t *testing.T, filter Filter, lastKillmailID int64) []int64 {
+	t.Helper()
+	var ids []int64
+	err := Replay(filter, lastKillmailID, func(event Event) bool {
+		ids = append(ids, event.Kill.KillmailID)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestReplayFollowsIngestionOrder(t *testing.T) {
+	kill := func(id int64) models.Kill {
+		return models.Kill{KillmailID: id, KillTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+
+	if err := db.UpsertKills([]models.Kill{kill(500)}); err != nil {
+		t.Fatal(err)
+	}
+	// A backfill stores older killmails after the client saw 500.
+	if err := db.UpsertKills([]models.Kill{kill(120), kill(110)}); err != nil {
+		t.Fatal(err)
+	}
+	// Upserting a kill again keeps its place.
+	if err := db.UpsertKills([]models.Kill{kill(500)}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := replayed(t, Filter{}, 500)
+	want := []int64{110, 120}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Replay after 500 = %v, want %v", got, want)
+	}
+
+	if got := replayed(t, Filter{}, 999999); len(got) != 0 {
+		t.Errorf("Replay after an unknown kill = %v, want nothing", got)
+	}
+}
+
+func TestReplayFiltersBeforePaging(t *testing.T) {
+	start := int64(10000)
+	if err := db.UpsertKills([]models.Kill{{KillmailID: start, KillTime: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// More than a page of kills, of which only the last few match.
+	var kills []models.Kill
+	for i := int64(1); i <= replayLimit+50; i++ {
+		kill := models.Kill{KillmailID: start + i, KillTime: time.Now(), TotalValue: 1}
+		if i > replayLimit+40 {
+			kill.TotalValue = 1e9
+		}
+		kills = append(kills, kill)
+	}
+	if err := db.UpsertKills(kills); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := replayed(t, Filter{MinValue: 1e9}, start); len(got) != 10 {
+		t.Errorf("Replay with a value filter returned %d kills, want 10", len(got))
+	}
+	if got := replayed(t, Filter{}, start); len(got) != replayLimit+50 {
+		t.Errorf("Replay returned %d kills, want %d", len(got), replayLimit+50)
+	}
+}
+
+func TestReplayMatchesAttackers(t *testing.T) {
+	start := int64(20000)
+	characterID := 90000001
+	if err := db.UpsertKills([]models.Kill{{KillmailID: start, KillTime: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	kills := []models.Kill{
+		{KillmailID: start + 1, KillTime: time.Now()},
+		{KillmailID: start + 2, KillTime: time.Now(), Attackers: models.AttackersJSON{{CharacterID: &characterID}}},
+	}
+	if err := db.UpsertKills(kills); err != nil {
+		t.Fatal(err)
+	}
+
+	got := replayed(t, Filter{CharacterIDs: []int64{int64(characterID)}}, start)
+	if fmt.Sprint(got) != fmt.Sprint([]int64{start + 2}) {
+		t.Errorf("Replay for an attacker = %v, want [%d]", got, start+2)
+	}
+}
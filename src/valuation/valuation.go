@@ -0,0 +1,115 @@
+package valuation
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tadeasf/eve-ran/src/db"
+	"github.com/tadeasf/eve-ran/src/db/models"
+)
+
+const (
+	batchSize = 500
+
+	// singletonBlueprintCopy marks blueprint copies, which have no market value.
+	singletonBlueprintCopy = 2
+)
+
+// ErrMissingPrices is returned by ValueKill when the kill's ship or one of its priced items has
+// no stored price yet. The kill is left unvalued, so it is valued in full once prices are fetched
+// rather than kept at a partial value.
+var ErrMissingPrices = errors.New("prices missing for some of the kill's types")
+
+// ValueKill recomputes a kill's destroyed and dropped values from stored prices at the kill date.
+// The victim's ship always counts as destroyed. Blueprint copies have no market value and need
+// no price.
+func ValueKill(kill *models.Kill) error {
+	typeIDs := []int{kill.Victim.ShipTypeID}
+	for _, item := range kill.Victim.Items {
+		if item.Singleton != singletonBlueprintCopy {
+			typeIDs = append(typeIDs, item.ItemTypeID)
+		}
+	}
+
+	prices, err := db.GetPricesAt(typeIDs, kill.KillTime)
+	if err != nil {
+		return err
+	}
+	for _, typeID := range typeIDs {
+		if _, ok := prices[typeID]; !ok {
+			return fmt.Errorf("%w: type %d", ErrMissingPrices, typeID)
+		}
+	}
+
+	destroyed := prices[kill.Victim.ShipTypeID]
+	dropped := 0.0
+	for _, item := range kill.Victim.Items {
+		if item.Singleton == singletonBlueprintCopy {
+			continue
+		}
+		price := prices[item.ItemTypeID]
+		if item.QuantityDestroyed != nil {
+			destroyed += price * float64(*item.QuantityDestroyed)
+		}
+		if item.QuantityDropped != nil {
+			dropped += price * float64(*item.QuantityDropped)
+		}
+	}
+
+	now := time.Now()
+	kill.ComputedDestroyedValue = destroyed
+	kill.ComputedDroppedValue = dropped
+	kill.ComputedTotalValue = destroyed + dropped
+	kill.ValuedAt = &now
+	return nil
+}
+
+// ValuePendingKills values every kill that has not been valued yet and returns how many were updated.
+// Kills with a type that has no stored price are skipped and stay pending.
+func ValuePendingKills() (int, error) {
+	total := 0
+	skipped := 0
+	lastFailed := int64(0)
+	after := int64(0)
+
+	for {
+		kills, err := db.GetUnvaluedKills(after, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(kills) == 0 {
+			if skipped > 0 {
+				log.Printf("Left %d kills with missing prices unvalued", skipped)
+			}
+			return total, nil
+		}
+
+		for i := range kills {
+			kill := &kills[i]
+			err := ValueKill(kill)
+			if errors.Is(err, ErrMissingPrices) {
+				skipped++
+				after = kill.KillmailID
+				continue
+			}
+			if err != nil {
+				log.Printf("Error valuing kill %d: %v", kill.KillmailID, err)
+				// Stop rather than loop forever on a kill that keeps failing.
+				if kill.KillmailID == lastFailed {
+					return total, err
+				}
+				lastFailed = kill.KillmailID
+				break
+			}
+			if err := db.UpdateKillValuation(kill); err != nil {
+				return total, err
+			}
+			after = kill.KillmailID
+			total++
+		}
+
+		log.Printf("Valued %d kills so far", total)
+	}
+}